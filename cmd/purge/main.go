@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// DefaultRetention is used when purge config is missing.
+const DefaultRetention = 30 * 24 * time.Hour
+
+var ErrInvalidRetention = errors.New("purge retention must be positive")
+
+func main() {
+	logger := logging.GetLogger()
+	appConf, err := config.LoadConfig()
+	if err != nil {
+		logger.WithError(err).Error("load config failed")
+
+		return
+	}
+	retention, err := purgeRetention(appConf.Purge)
+	if err != nil {
+		logger.WithError(err).Error("invalid purge config")
+
+		return
+	}
+	ctx := context.Background()
+	ctx = logging.WithContext(ctx, logger)
+
+	dbConn, err := db.Connect(ctx, appConf.DB)
+	if err != nil {
+		logger.WithError(err).Error("db connect failed")
+
+		return
+	}
+	defer func() {
+		if closeErr := db.Disconnect(dbConn); closeErr != nil {
+			logger.WithError(closeErr).Error("db disconnect failed")
+		}
+	}()
+
+	deletedBefore := time.Now().UTC().Add(-retention)
+	purged, err := db.PurgeDeletedCompanies(ctx, dbConn, deletedBefore)
+	if err != nil {
+		logger.WithError(err).Error("purge companies failed")
+
+		return
+	}
+	logger.
+		WithFields(logging.Fields{
+			"purged":         purged,
+			"deleted_before": deletedBefore,
+		}).
+		Info("purge companies succeeded")
+
+	now := time.Now().UTC()
+	purgedKeys, err := db.PurgeExpiredIdempotencyKeys(ctx, dbConn, now)
+	if err != nil {
+		logger.WithError(err).Error("purge idempotency keys failed")
+
+		return
+	}
+	logger.
+		WithFields(logging.Fields{
+			"purged":         purgedKeys,
+			"expired_before": now,
+		}).
+		Info("purge idempotency keys succeeded")
+}
+
+// purgeRetention returns retention of the config, default one is used for missing config.
+// Non-positive retention is rejected, it would purge every deleted company at once.
+func purgeRetention(purgeConf *config.Purge) (time.Duration, error) {
+	if purgeConf == nil {
+		return DefaultRetention, nil
+	}
+	if purgeConf.Retention <= 0 {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidRetention, purgeConf.Retention)
+	}
+
+	return purgeConf.Retention, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	"github.com/pzabolotniy/logging/pkg/logging"
@@ -10,6 +11,11 @@ import (
 )
 
 func main() {
+	isAdmin := flag.Bool("admin", false, "issue token of the admin client")
+	subject := flag.String("subject", "", "name of the client recorded in the change history of companies")
+	tenant := flag.String("tenant", "", "tenant of the client, the default tenant if empty")
+	flag.Parse()
+
 	logger := logging.GetLogger()
 	appConf, err := config.LoadConfig()
 	if err != nil {
@@ -18,7 +24,17 @@ func main() {
 		return
 	}
 	tokenService := authn.NewTokenService(appConf.ClientToken)
-	token, err := tokenService.IssueToken()
+	opts := make([]authn.TokenOption, 0)
+	if *isAdmin {
+		opts = append(opts, authn.AsAdmin())
+	}
+	if *subject != "" {
+		opts = append(opts, authn.WithSubject(*subject))
+	}
+	if *tenant != "" {
+		opts = append(opts, authn.WithTenant(*tenant))
+	}
+	token, err := tokenService.IssueToken(opts...)
 	if err != nil {
 		logger.WithError(err).Error("issue token failed")
 
@@ -11,7 +11,9 @@ import (
 	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/geoip"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/migration"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/outbox"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/webapi"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/webhook"
 )
 
 func main() {
@@ -44,8 +46,24 @@ func main() {
 		return
 	}
 
+	publisher, err := outbox.NewPublisher(appConf.Outbox)
+	if err != nil {
+		logger.WithError(err).Error("create outbox publisher failed")
+
+		return
+	}
+	publisher = outbox.MultiPublisher{publisher, webhook.NewPublisher(dbConn)}
+	relay := outbox.NewRelay(dbConn, publisher, appConf.Outbox)
+	go relay.Run(ctx)
+	dispatcher := webhook.NewDispatcher(dbConn, appConf.Webhooks)
+	go dispatcher.Run(ctx)
+
 	handler := &webapi.HandlerEnv{
-		DbConn: dbConn,
+		DbConn:          dbConn,
+		SearchConf:      appConf.Search,
+		IdempotencyConf: appConf.Idempotency,
+		EventsConf:      appConf.Events,
+		WebhooksConf:    appConf.Webhooks,
 	}
 	geoIPService := geoip.NewGeoIPService(appConf.GeoIP)
 	tokenService := authn.NewTokenService(appConf.ClientToken)
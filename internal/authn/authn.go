@@ -12,10 +12,18 @@ import (
 )
 
 var (
-	ErrInvalidToken     = errors.New("invalid token")
-	ErrTokenUnknownType = errors.New("unknown token type")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrTokenUnknownType   = errors.New("unknown token type")
+	ErrInvalidTokenTenant = errors.New("invalid tenant of the token")
+	ErrInvalidTokenActor  = errors.New("invalid issuer or subject of the token")
 )
 
+// MaxTenantIDLength is the max length of the tenant claim, it is the length of tenant_id columns.
+const MaxTenantIDLength = 64
+
+// MaxActorLength is the max length of the actor, it is the length of created_by, actor and owner columns.
+const MaxActorLength = 255
+
 type TokenService struct {
 	Conf *config.ClientToken
 }
@@ -26,9 +34,65 @@ func NewTokenService(conf *config.ClientToken) *TokenService {
 
 type ClientAPIToken struct {
 	jwt.RegisteredClaims
+	// Admin clients can see deleted companies.
+	Admin bool `json:"admin,omitempty"`
+	// TenantID limits the client to companies of the tenant, empty is the default tenant.
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// Principal is the stable identity of the client, it is the subject qualified by the issuer as iss/sub,
+// empty if there is no subject. Subjects of different issuers do not collide.
+func (t *ClientAPIToken) Principal() string {
+	if t.Subject == "" {
+		return ""
+	}
+
+	return qualifyByIssuer(t.Issuer, t.Subject)
 }
 
-func (ts *TokenService) IssueToken() (string, error) {
+// Actor identifies the client in the audit trail, it is the principal
+// or the token ID qualified by the issuer if there is no principal.
+func (t *ClientAPIToken) Actor() string {
+	if principal := t.Principal(); principal != "" {
+		return principal
+	}
+
+	return qualifyByIssuer(t.Issuer, t.ID)
+}
+
+func qualifyByIssuer(issuer, name string) string {
+	if issuer == "" {
+		return name
+	}
+
+	return issuer + "/" + name
+}
+
+// TokenOption sets optional claims of the issued token.
+type TokenOption func(claims *ClientAPIToken)
+
+// AsAdmin issues the token of the admin client.
+func AsAdmin() TokenOption {
+	return func(claims *ClientAPIToken) {
+		claims.Admin = true
+	}
+}
+
+// WithSubject issues the token of the named client.
+func WithSubject(subject string) TokenOption {
+	return func(claims *ClientAPIToken) {
+		claims.Subject = subject
+	}
+}
+
+// WithTenant issues the token of the client of the tenant.
+func WithTenant(tenantID string) TokenOption {
+	return func(claims *ClientAPIToken) {
+		claims.TenantID = tenantID
+	}
+}
+
+func (ts *TokenService) IssueToken(opts ...TokenOption) (string, error) {
 	now := time.Now().UTC()
 	conf := ts.Conf
 	expiresAt := now.Add(conf.TTL)
@@ -36,13 +100,22 @@ func (ts *TokenService) IssueToken() (string, error) {
 	issuer := conf.Issuer
 	tokenID := uuid.New()
 	claims := &ClientAPIToken{
-		jwt.RegisteredClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    issuer,
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(issuedAt),
 			ID:        tokenID.String(),
 		},
 	}
+	for _, opt := range opts {
+		opt(claims)
+	}
+	if len(claims.TenantID) > MaxTenantIDLength {
+		return "", ErrInvalidTokenTenant
+	}
+	if len(claims.Actor()) > MaxActorLength {
+		return "", ErrInvalidTokenActor
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	secret := conf.Secret
 
@@ -70,6 +143,12 @@ func (ts *TokenService) ValidateToken(clientJWT string) (*ClientAPIToken, error)
 	if !ok {
 		return nil, ErrTokenUnknownType
 	}
+	if len(claims.TenantID) > MaxTenantIDLength {
+		return nil, ErrInvalidTokenTenant
+	}
+	if len(claims.Actor()) > MaxActorLength {
+		return nil, ErrInvalidTokenActor
+	}
 
 	return claims, nil
 }
@@ -0,0 +1,45 @@
+package authn
+
+import "context"
+
+type clientTokenCtxKey struct{}
+
+// WithClientToken stores validated client token in the context.
+func WithClientToken(ctx context.Context, token *ClientAPIToken) context.Context {
+	return context.WithValue(ctx, clientTokenCtxKey{}, token)
+}
+
+// ClientTokenFromContext returns validated client token, nil if the client is anonymous.
+func ClientTokenFromContext(ctx context.Context) *ClientAPIToken {
+	token, _ := ctx.Value(clientTokenCtxKey{}).(*ClientAPIToken)
+
+	return token
+}
+
+// IsAdmin reports whether the client of the request is admin.
+func IsAdmin(ctx context.Context) bool {
+	token := ClientTokenFromContext(ctx)
+
+	return token != nil && token.Admin
+}
+
+// Principal returns the stable identity of the client of the request,
+// empty for anonymous client and for the token without subject.
+func Principal(ctx context.Context) string {
+	token := ClientTokenFromContext(ctx)
+	if token == nil {
+		return ""
+	}
+
+	return token.Principal()
+}
+
+// Actor returns the actor of the client of the request, empty for anonymous client.
+func Actor(ctx context.Context) string {
+	token := ClientTokenFromContext(ctx)
+	if token == nil {
+		return ""
+	}
+
+	return token.Actor()
+}
@@ -12,6 +12,12 @@ type App struct {
 	WebAPI      *WebAPI      `mapstructure:"web_api"`
 	GeoIP       *GeoIP       `mapstructure:"geoip"` //nolint:tagliatelle // need to discuss
 	ClientToken *ClientToken `mapstructure:"client_token"`
+	Purge       *Purge       `mapstructure:"purge"`
+	Search      *Search      `mapstructure:"search"`
+	Idempotency *Idempotency `mapstructure:"idempotency"`
+	Outbox      *Outbox      `mapstructure:"outbox"`
+	Webhooks    *Webhooks    `mapstructure:"webhooks"`
+	Events      *Events      `mapstructure:"events"`
 }
 
 type DB struct {
@@ -38,6 +44,51 @@ type ClientToken struct {
 	TTL    time.Duration `mapstructure:"ttl"`
 }
 
+type Purge struct {
+	// Retention is how long soft deleted companies are kept before purge.
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+type Search struct {
+	// MaxIDs limits companies_ids of one search request, 0 means no limit.
+	MaxIDs int `mapstructure:"max_ids"`
+}
+
+type Idempotency struct {
+	// TTL is how long the response is replayed for the same Idempotency-Key.
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+type Outbox struct {
+	// Publisher is log or file.
+	Publisher string `mapstructure:"publisher"`
+	// FilePath is the file the events are appended to by file publisher.
+	FilePath     string        `mapstructure:"file_path"`
+	BatchSize    int           `mapstructure:"batch_size"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+type Webhooks struct {
+	// MaxAttempts is the number of failed attempts after which the delivery is dead.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// BackoffBase is the delay after the first failed attempt, it is doubled after every next one up to BackoffMax.
+	BackoffBase  time.Duration `mapstructure:"backoff_base"`
+	BackoffMax   time.Duration `mapstructure:"backoff_max"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+	BatchSize    int           `mapstructure:"batch_size"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// AllowPrivateNetworks lets webhooks to loopback and private addresses, it is meant for local runs and tests.
+	AllowPrivateNetworks bool `mapstructure:"allow_private_networks"`
+}
+
+// Events configures the stream of company events.
+type Events struct {
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// HeartbeatInterval is the interval of comments sent to keep idle connections open.
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+	BatchSize         int           `mapstructure:"batch_size"`
+}
+
 func LoadConfig() (*App, error) {
 	viper.SetConfigName("config") // hardcoded config name
 	viper.SetConfigType("yaml")   // hardcoded extension
@@ -3,68 +3,354 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/pzabolotniy/logging/pkg/logging"
 )
 
+// FirstCompanyVersion is the version of just created company.
+const FirstCompanyVersion = 1
+
+// ErrCompanyNotFound is returned when the company does not exist.
+// It wraps sql.ErrNoRows, so it is handled as missing row as well.
+var ErrCompanyNotFound = fmt.Errorf("company not found: %w", sql.ErrNoRows)
+
+// ErrCompanyVersionMismatch is returned when company was changed by someone else.
+var ErrCompanyVersionMismatch = errors.New("company version mismatch")
+
+// uniqueViolationCode is SQLSTATE of unique_violation error.
+const uniqueViolationCode = "23505"
+
+// CompanyConflictError is returned when another company has the same code in the same country.
+type CompanyConflictError struct {
+	// ExistingCompanyID is uuid.Nil when the company was written concurrently,
+	// it is found by GetConflictingCompanyID after the transaction.
+	ExistingCompanyID uuid.UUID
+	// Company is the company being written, it is nil for batches.
+	Company *Company
+}
+
+func (e *CompanyConflictError) Error() string {
+	return fmt.Sprintf("company with the same code and country already exists: %s", e.ExistingCompanyID)
+}
+
+const companyColumns = `id, name, code, country, website, phone, description, employees_count, registered, type,
+    created_at, updated_at, version, deleted_at, parent_id, tenant_id, created_by, updated_by, ` + companyTagsColumn
+
+// CompanyType is the legal form of the company.
+type CompanyType string
+
+const (
+	CompanyTypeCorporation        CompanyType = "Corporation"
+	CompanyTypeNonProfit          CompanyType = "NonProfit"
+	CompanyTypeCooperative        CompanyType = "Cooperative"
+	CompanyTypeSoleProprietorship CompanyType = "Sole Proprietorship"
+)
+
+// CompanyTypes lists all company types in the order of the companies_type_check constraint.
+var CompanyTypes = []CompanyType{
+	CompanyTypeCorporation,
+	CompanyTypeNonProfit,
+	CompanyTypeCooperative,
+	CompanyTypeSoleProprietorship,
+}
+
 type Company struct {
 	Name      string    `db:"name"`
 	Code      string    `db:"code"`
 	Country   string    `db:"country"`
 	WebSite   string    `db:"website"`
 	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
 	Phone     string    `db:"phone"`
-	ID        uuid.UUID `db:"id"`
+	// Description is up to 3000 characters.
+	Description    string      `db:"description"`
+	EmployeesCount int         `db:"employees_count"`
+	Registered     bool        `db:"registered"`
+	Type           CompanyType `db:"type"`
+	ID             uuid.UUID   `db:"id"`
+	Version        int64       `db:"version"`
+	// DeletedAt is set for soft deleted company.
+	DeletedAt *time.Time `db:"deleted_at"`
+	// ParentID is the parent company of the subsidiary, it is changed by SetCompanyParent only.
+	ParentID *uuid.UUID `db:"parent_id"`
+	// Tags are read with the company, they are changed by AttachCompanyTags and DetachCompanyTag.
+	Tags TagNames `db:"tags"`
+	// TenantID is set from the context by the functions creating companies.
+	TenantID string `db:"tenant_id"`
+	// CreatedBy and UpdatedBy are actors of the clients created and last changed the company.
+	CreatedBy string `db:"created_by"`
+	UpdatedBy string `db:"updated_by"`
 }
 
 type NamedExerContext interface {
 	NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error)
 }
 
-func CreateCompany(ctx context.Context, dbConn NamedExerContext, dbCompany *Company) error {
-	logger := logging.FromContext(ctx)
-	query := `INSERT INTO companies (
-    id, name, code, country, website, phone, created_at
+type NamedExecQueryerContext interface {
+	NamedExerContext
+	RowxQueryerContext
+}
+
+type ExecNamedExerContext interface {
+	sqlx.ExecerContext
+	NamedExerContext
+}
+
+type ExecQueryerContext interface {
+	sqlx.ExecerContext
+	RowxQueryerContext
+}
+
+// insertCompanyQuery is expanded by sqlx into a multi-row insert when a slice of companies is passed.
+const insertCompanyQuery = `INSERT INTO companies (
+    id, name, code, country, website, phone, description, employees_count, registered, type,
+    created_at, updated_at, version, tenant_id, created_by, updated_by
 ) VALUES (
-	:id, :name, :code, :country, :website, :phone, :created_at
+	:id, :name, :code, :country, :website, :phone, :description, :employees_count, :registered, :type,
+	:created_at, :updated_at, :version, :tenant_id, :created_by, :updated_by
 )`
-	_, err := dbConn.NamedExecContext(ctx, query, dbCompany)
+
+func CreateCompany(ctx context.Context, dbConn NamedExecQueryerContext, dbCompany *Company) error {
+	logger := logging.FromContext(ctx)
+	dbCompany.TenantID = TenantID(ctx)
+	if err := checkCompanyConflict(ctx, dbConn, dbCompany); err != nil {
+		return err
+	}
+	_, err := dbConn.NamedExecContext(ctx, insertCompanyQuery, dbCompany)
 	if err != nil {
 		logger.WithError(err).Error("insert company failed")
 
+		return translateCompanyError(err, dbCompany)
+	}
+
+	return nil
+}
+
+// UpdateCompany saves the company if its version in the database is still dbCompany.Version.
+// On success dbCompany.Version is set to the new version.
+func UpdateCompany(ctx context.Context, dbConn NamedExecQueryerContext, dbCompany *Company) error {
+	logger := logging.FromContext(ctx)
+	// the company of another tenant is not found
+	dbCompany.TenantID = TenantID(ctx)
+	if err := checkCompanyConflict(ctx, dbConn, dbCompany); err != nil {
+		return err
+	}
+	query := `UPDATE companies SET
+    name = :name, code = :code, country = :country, website = :website, phone = :phone,
+    description = :description, employees_count = :employees_count, registered = :registered, type = :type,
+    updated_at = :updated_at, updated_by = :updated_by, version = version + 1
+WHERE id = :id AND tenant_id = :tenant_id AND version = :version AND deleted_at IS NULL`
+	result, err := dbConn.NamedExecContext(ctx, query, dbCompany)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", dbCompany.ID).Error("update company failed")
+
+		return translateCompanyError(err, dbCompany)
+	}
+	err = checkVersionedWrite(ctx, dbConn, dbCompany.ID, result)
+	if err != nil {
 		return err
 	}
+	dbCompany.Version++
 
 	return nil
 }
 
-func DeleteCompanyByID(ctx context.Context, dbConn sqlx.ExecerContext, companyID uuid.UUID) error {
+// checkCompanyConflict returns CompanyConflictError if another company of the tenant has the same code and country.
+func checkCompanyConflict(ctx context.Context, dbConn RowxQueryerContext, dbCompany *Company) error {
+	existingCompanyID, err := GetConflictingCompanyID(ctx, dbConn, dbCompany)
+	if err != nil {
+		return err
+	}
+	if existingCompanyID == uuid.Nil {
+		return nil
+	}
+
+	return &CompanyConflictError{ExistingCompanyID: existingCompanyID, Company: dbCompany}
+}
+
+// GetConflictingCompanyID returns ID of another not deleted company of the tenant
+// having the same code and country as dbCompany, uuid.Nil if there is no such company.
+func GetConflictingCompanyID(ctx context.Context, dbConn RowxQueryerContext, dbCompany *Company) (uuid.UUID, error) {
+	logger := logging.FromContext(ctx)
+	var existingCompanyID uuid.UUID
+	query := `SELECT id FROM companies
+WHERE tenant_id = $1 AND code = $2 AND upper(country) = upper($3) AND id <> $4 AND deleted_at IS NULL`
+	err := dbConn.QueryRowxContext(ctx, query, TenantID(ctx), dbCompany.Code, dbCompany.Country, dbCompany.ID).
+		Scan(&existingCompanyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		logger.WithError(err).WithField("company_id", dbCompany.ID).Error("select conflicting company failed")
+
+		return uuid.Nil, err
+	}
+
+	return existingCompanyID, nil
+}
+
+// translateCompanyError turns unique violation of concurrent writes into CompanyConflictError,
+// dbCompany is the company being written, nil for batches.
+// The transaction is aborted by the violation, so the conflicting company is not looked up here.
+func translateCompanyError(err error, dbCompany *Company) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode && pgErr.ConstraintName == "companies_code_country_key" {
+		return &CompanyConflictError{Company: dbCompany}
+	}
+
+	return err
+}
+
+// DeleteCompanyByIDAndVersion soft deletes the company only if it was not changed since version,
+// deletedBy is the actor of the client deleting the company.
+func DeleteCompanyByIDAndVersion(
+	ctx context.Context, dbConn ExecQueryerContext, companyID uuid.UUID, version int64, deletedAt time.Time,
+	deletedBy string,
+) error {
 	logger := logging.FromContext(ctx)
-	query := `DELETE FROM companies WHERE id = $1`
-	_, err := dbConn.ExecContext(ctx, query, companyID)
+	query := `UPDATE companies SET deleted_at = $4, updated_at = $4, updated_by = $5, version = version + 1
+WHERE id = $1 AND tenant_id = $2 AND version = $3 AND deleted_at IS NULL`
+	result, err := dbConn.ExecContext(ctx, query, companyID, TenantID(ctx), version, deletedAt, deletedBy)
 	if err != nil {
 		logger.WithError(err).WithField("company_id", companyID).Error("delete company failed")
 
 		return err
 	}
 
+	return checkVersionedWrite(ctx, dbConn, companyID, result)
+}
+
+// checkVersionedWrite tells apart missing company and version mismatch
+// when a write conditioned by version affected nothing.
+func checkVersionedWrite(ctx context.Context, dbConn RowxQueryerContext, companyID uuid.UUID, result sql.Result) error {
+	logger := logging.FromContext(ctx)
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("get affected rows failed")
+
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	exists, err := companyExists(ctx, dbConn, companyID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCompanyNotFound
+	}
+
+	return ErrCompanyVersionMismatch
+}
+
+// companyExists reports whether the company of the tenant exists, even soft deleted.
+func companyExists(ctx context.Context, dbConn RowxQueryerContext, companyID uuid.UUID) (bool, error) {
+	logger := logging.FromContext(ctx)
+	exists := false
+	query := `SELECT EXISTS(SELECT 1 FROM companies WHERE id = $1 AND tenant_id = $2)`
+	err := dbConn.QueryRowxContext(ctx, query, companyID, TenantID(ctx)).Scan(&exists)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("check company existence failed")
+
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// RestoreCompany undoes soft delete of the company if it was not changed since dbCompany.Version.
+// On success dbCompany.Version is set to the new version.
+func RestoreCompany(ctx context.Context, dbConn NamedExecQueryerContext, dbCompany *Company) error {
+	logger := logging.FromContext(ctx)
+	dbCompany.TenantID = TenantID(ctx)
+	if err := checkCompanyConflict(ctx, dbConn, dbCompany); err != nil {
+		return err
+	}
+	query := `UPDATE companies SET deleted_at = NULL, updated_at = :updated_at, updated_by = :updated_by,
+    version = version + 1
+WHERE id = :id AND tenant_id = :tenant_id AND version = :version AND deleted_at IS NOT NULL`
+	result, err := dbConn.NamedExecContext(ctx, query, dbCompany)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", dbCompany.ID).Error("restore company failed")
+
+		return translateCompanyError(err, dbCompany)
+	}
+	err = checkVersionedWrite(ctx, dbConn, dbCompany.ID, result)
+	if err != nil {
+		return err
+	}
+	dbCompany.Version++
+	dbCompany.DeletedAt = nil
+
 	return nil
 }
 
+// PurgeDeletedCompanies permanently removes companies of all tenants soft deleted before deletedBefore.
+func PurgeDeletedCompanies(ctx context.Context, dbConn sqlx.ExecerContext, deletedBefore time.Time) (int64, error) {
+	logger := logging.FromContext(ctx)
+	query := `DELETE FROM companies WHERE deleted_at < $1`
+	result, err := dbConn.ExecContext(ctx, query, deletedBefore)
+	if err != nil {
+		logger.WithError(err).Error("purge companies failed")
+
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		logger.WithError(err).Error("get affected rows failed")
+
+		return 0, err
+	}
+
+	return purged, nil
+}
+
 type RowxQueryerContext interface {
 	QueryRowxContext(ctx context.Context, query string, args ...any) *sqlx.Row
 }
 
+// GetCompanyByID returns not deleted company.
 func GetCompanyByID(ctx context.Context, dbConn RowxQueryerContext, companyID uuid.UUID) (*Company, error) {
+	query := `SELECT ` + companyColumns + `
+FROM companies
+WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`
+
+	return getCompany(ctx, dbConn, query, companyID)
+}
+
+// GetCompanyByIDWithDeleted returns the company even if it was soft deleted.
+func GetCompanyByIDWithDeleted(ctx context.Context, dbConn RowxQueryerContext, companyID uuid.UUID) (*Company, error) {
+	query := `SELECT ` + companyColumns + `
+FROM companies
+WHERE id = $1 AND tenant_id = $2`
+
+	return getCompany(ctx, dbConn, query, companyID)
+}
+
+// GetCompanyByIDForUpdate returns the company even if it was soft deleted
+// and locks it until the end of the transaction.
+func GetCompanyByIDForUpdate(ctx context.Context, dbConn RowxQueryerContext, companyID uuid.UUID) (*Company, error) {
+	query := `SELECT ` + companyColumns + `
+FROM companies
+WHERE id = $1 AND tenant_id = $2
+FOR UPDATE`
+
+	return getCompany(ctx, dbConn, query, companyID)
+}
+
+// getCompany runs the query selecting the company by ID ($1) and tenant ($2).
+func getCompany(ctx context.Context, dbConn RowxQueryerContext, query string, companyID uuid.UUID) (*Company, error) {
 	logger := logging.FromContext(ctx)
 	dbCompany := new(Company)
-	query := `SELECT id, name, code, country, website, phone, created_at
-FROM companies
-WHERE id = $1`
-	err := dbConn.QueryRowxContext(ctx, query, companyID).StructScan(dbCompany)
+	err := dbConn.QueryRowxContext(ctx, query, companyID, TenantID(ctx)).StructScan(dbCompany)
 	if err != nil {
 		logger.
 			WithError(err).
@@ -77,17 +363,19 @@ WHERE id = $1`
 	return dbCompany, nil
 }
 
-func GetCompaniesListByID(ctx context.Context, dbConn *sqlx.DB, companyIDs []uuid.UUID) ([]Company, error) {
+func GetCompaniesListByID(
+	ctx context.Context, dbConn *sqlx.DB, companyIDs []uuid.UUID, withDeleted bool,
+) ([]Company, error) {
 	if len(companyIDs) == 0 {
 		return []Company{}, nil
 	}
 	logger := logging.FromContext(ctx)
 	list := make([]Company, 0)
-	query := `SELECT id, name, code, country, website, phone, created_at
+	query := `SELECT ` + companyColumns + `
 FROM companies
-WHERE id IN (?)
+WHERE id IN (?) AND tenant_id = ? AND (? OR deleted_at IS NULL)
 ORDER BY created_at DESC`
-	query, args, err := sqlx.In(query, companyIDs)
+	query, args, err := sqlx.In(query, companyIDs, TenantID(ctx), withDeleted)
 	if err != nil {
 		logger.WithError(err).Error("prepare SELECT-query failed")
 
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+// CreateCompanies inserts all companies by one statement.
+// CompanyConflictError is returned if any company conflicts with existing one or with another one of the batch.
+func CreateCompanies(ctx context.Context, dbConn NamedExerContext, dbCompanies []Company) error {
+	if len(dbCompanies) == 0 {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+	setCompaniesTenantID(ctx, dbCompanies)
+	_, err := dbConn.NamedExecContext(ctx, insertCompanyQuery, dbCompanies)
+	if err != nil {
+		logger.WithError(err).WithField("companies_count", len(dbCompanies)).Error("insert companies failed")
+
+		return translateCompanyError(err, nil)
+	}
+
+	return nil
+}
+
+// CreateCompaniesSkipConflicts inserts companies by one statement skipping the ones
+// which conflict with existing companies or with previous companies of the batch.
+// IDs of inserted companies are returned.
+func CreateCompaniesSkipConflicts(
+	ctx context.Context, dbConn sqlx.QueryerContext, dbCompanies []Company,
+) (map[uuid.UUID]bool, error) {
+	createdIDs := make(map[uuid.UUID]bool, len(dbCompanies))
+	if len(dbCompanies) == 0 {
+		return createdIDs, nil
+	}
+	logger := logging.FromContext(ctx)
+	setCompaniesTenantID(ctx, dbCompanies)
+	query, args, err := sqlx.Named(insertCompanyQuery+`
+ON CONFLICT (tenant_id, code, upper(country)) WHERE deleted_at IS NULL DO NOTHING
+RETURNING id`, dbCompanies)
+	if err != nil {
+		logger.WithError(err).Error("prepare INSERT-query failed")
+
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(dbCompanies))
+	err = sqlx.SelectContext(ctx, dbConn, &ids, sqlx.Rebind(sqlx.DOLLAR, query), args...)
+	if err != nil {
+		logger.WithError(err).WithField("companies_count", len(dbCompanies)).Error("insert companies failed")
+
+		return nil, err
+	}
+	for _, id := range ids {
+		createdIDs[id] = true
+	}
+
+	return createdIDs, nil
+}
+
+// setCompaniesTenantID sets the tenant of the request to the companies being created.
+func setCompaniesTenantID(ctx context.Context, dbCompanies []Company) {
+	tenantID := TenantID(ctx)
+	for i := range dbCompanies {
+		dbCompanies[i].TenantID = tenantID
+	}
+}
+
+// companyCodesChunkSize keeps the query below the limit of query parameters.
+const companyCodesChunkSize = 1000
+
+// CompanyCode is the code of the company in the country, the country is compared case-insensitively.
+type CompanyCode struct {
+	Code    string
+	Country string
+}
+
+// GetCompaniesByCodes returns not deleted companies of the tenant having any of the codes.
+func GetCompaniesByCodes(ctx context.Context, dbConn sqlx.QueryerContext, codes []CompanyCode) ([]Company, error) {
+	logger := logging.FromContext(ctx)
+	list := make([]Company, 0)
+	for start := 0; start < len(codes); start += companyCodesChunkSize {
+		end := start + companyCodesChunkSize
+		if end > len(codes) {
+			end = len(codes)
+		}
+		qArgs := new(queryArgs)
+		tenant := tenantCondition(ctx, qArgs)
+		tuples := make([]string, 0, end-start)
+		for _, code := range codes[start:end] {
+			tuples = append(tuples, "("+qArgs.add(code.Code)+", upper("+qArgs.add(code.Country)+"))")
+		}
+		query := `SELECT ` + companyColumns + `
+FROM companies
+WHERE ` + tenant + ` AND deleted_at IS NULL AND (code, upper(country)) IN (` + strings.Join(tuples, ", ") + `)`
+		chunk := make([]Company, 0)
+		err := sqlx.SelectContext(ctx, dbConn, &chunk, query, qArgs.args...)
+		if err != nil {
+			logger.WithError(err).WithField("codes_count", end-start).Error("select companies by codes failed")
+
+			return nil, err
+		}
+		list = append(list, chunk...)
+	}
+
+	return list, nil
+}
+
+// DeleteCompaniesParams selects not deleted companies by IDs or by filter.
+type DeleteCompaniesParams struct {
+	CompanyIDs []uuid.UUID
+	// Filter is combined with CompanyIDs if both are set, its WithDeleted is ignored.
+	Filter    *CompanyFilter
+	DeletedAt time.Time
+	// DeletedBy is the actor of the client deleting the companies.
+	DeletedBy string
+}
+
+// conditions returns SQL conditions selecting companies to delete.
+func (p *DeleteCompaniesParams) conditions(ctx context.Context, qArgs *queryArgs) []string {
+	filter := CompanyFilter{}
+	if p.Filter != nil {
+		filter = *p.Filter
+	}
+	filter.WithDeleted = false
+	conditions := filter.conditions(ctx, qArgs)
+	if p.CompanyIDs != nil {
+		placeholders := make([]string, 0, len(p.CompanyIDs))
+		for _, companyID := range p.CompanyIDs {
+			placeholders = append(placeholders, qArgs.add(companyID))
+		}
+		if len(placeholders) == 0 {
+			placeholders = append(placeholders, "NULL")
+		}
+		conditions = append(conditions, "id IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	return conditions
+}
+
+// DeleteCompanies soft deletes the selected companies and returns them as they were before deletion.
+// Companies are locked in the order of IDs, so concurrent deletions of the same companies do not deadlock.
+func DeleteCompanies(ctx context.Context, dbConn sqlx.QueryerContext, params *DeleteCompaniesParams) ([]Company, error) {
+	logger := logging.FromContext(ctx)
+	qArgs := new(queryArgs)
+	deletedAt := qArgs.add(params.DeletedAt)
+	deletedBy := qArgs.add(params.DeletedBy)
+	query := `WITH selected AS (
+    SELECT ` + companyColumns + `
+    FROM companies
+    WHERE ` + strings.Join(params.conditions(ctx, qArgs), " AND ") + `
+    ORDER BY id
+    FOR UPDATE
+), deleted AS (
+    UPDATE companies SET deleted_at = ` + deletedAt + `, updated_at = ` + deletedAt + `, updated_by = ` + deletedBy + `,
+        version = companies.version + 1
+    FROM selected
+    WHERE companies.id = selected.id
+    RETURNING companies.id
+)
+SELECT selected.* FROM selected JOIN deleted USING (id)
+ORDER BY id`
+	deleted := make([]Company, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &deleted, query, qArgs.args...)
+	if err != nil {
+		logger.WithError(err).Error("delete companies failed")
+
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// SelectCompaniesToDelete returns companies which DeleteCompanies would delete.
+func SelectCompaniesToDelete(
+	ctx context.Context, dbConn sqlx.QueryerContext, params *DeleteCompaniesParams,
+) ([]Company, error) {
+	logger := logging.FromContext(ctx)
+	qArgs := new(queryArgs)
+	query := `SELECT ` + companyColumns + `
+FROM companies
+WHERE ` + strings.Join(params.conditions(ctx, qArgs), " AND ") + `
+ORDER BY id`
+	list := make([]Company, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, qArgs.args...)
+	if err != nil {
+		logger.WithError(err).Error("select companies to delete failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+// ExportCompanies reads companies matching the filter in the order of IDs through the server-side cursor
+// and passes them to fn by batches of batchSize, so the companies are never loaded into memory all at once.
+// All batches are read from the same snapshot, fn is not called if there are no companies.
+func ExportCompanies(
+	ctx context.Context, dbConn *sqlx.DB, filter *CompanyFilter, batchSize int, fn func(companies []Company) error,
+) error {
+	logger := logging.FromContext(ctx)
+	tx, err := dbConn.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		logger.WithError(err).Error("begin transaction failed")
+
+		return err
+	}
+	// the transaction only reads, so it is never committed
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logger.WithError(rollbackErr).Error("rollback transaction failed")
+		}
+	}()
+
+	qArgs := new(queryArgs)
+	conditions := filter.conditions(ctx, qArgs)
+	query := `DECLARE companies_export NO SCROLL CURSOR FOR
+SELECT ` + companyColumns + `
+FROM companies
+WHERE ` + strings.Join(conditions, " AND ") + `
+ORDER BY id`
+	if _, err = tx.ExecContext(ctx, query, qArgs.args...); err != nil {
+		logger.WithError(err).Error("declare companies cursor failed")
+
+		return err
+	}
+
+	fetchQuery := `FETCH FORWARD ` + strconv.Itoa(batchSize) + ` FROM companies_export`
+	for {
+		batch := make([]Company, 0, batchSize)
+		if err = sqlx.SelectContext(ctx, tx, &batch, fetchQuery); err != nil {
+			logger.WithError(err).Error("fetch companies failed")
+
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err = fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+type CompanySortField string
+
+const (
+	SortByCreatedAt CompanySortField = "created_at"
+	SortByName      CompanySortField = "name"
+)
+
+type CompanySort struct {
+	Field CompanySortField
+	Desc  bool
+}
+
+// CompanyFilter limits the list of companies, zero value matches all not deleted companies of the tenant.
+type CompanyFilter struct {
+	Country      string
+	CodePrefix   string
+	NameContains string
+	// CreatedFrom is inclusive.
+	CreatedFrom *time.Time
+	// CreatedTo is exclusive.
+	CreatedTo *time.Time
+	// AddressCity and AddressCountry match companies having the address in the city and the country.
+	AddressCity    string
+	AddressCountry string
+	// Tags match companies having any or all of the tag names (depending on TagsMatch), names must be unique.
+	Tags        []string
+	TagsMatch   TagsMatch
+	WithDeleted bool
+}
+
+type CompanyListParams struct {
+	Filter CompanyFilter
+	Sort   CompanySort
+	// After is the last company of the previous page, nil for the first page.
+	After *Company
+	Limit int
+}
+
+// ListCompanies returns the page of companies using keyset pagination on the sort field and id.
+func ListCompanies(ctx context.Context, dbConn sqlx.QueryerContext, params *CompanyListParams) ([]Company, error) {
+	logger := logging.FromContext(ctx)
+	qArgs := new(queryArgs)
+	conditions := params.Filter.conditions(ctx, qArgs)
+
+	sortColumn := string(params.Sort.Field)
+	direction, comparison := "ASC", ">"
+	if params.Sort.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if after := params.After; after != nil {
+		var afterValue any = after.CreatedAt
+		if params.Sort.Field == SortByName {
+			afterValue = after.Name
+		}
+		conditions = append(conditions,
+			"("+sortColumn+", id) "+comparison+" ("+qArgs.add(afterValue)+", "+qArgs.add(after.ID)+")",
+		)
+	}
+
+	query := `SELECT ` + companyColumns + `
+FROM companies
+WHERE ` + strings.Join(conditions, " AND ") + `
+ORDER BY ` + sortColumn + ` ` + direction + `, id ` + direction + `
+LIMIT ` + qArgs.add(params.Limit)
+	list := make([]Company, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, qArgs.args...)
+	if err != nil {
+		logger.WithError(err).Error("select companies failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// conditions returns SQL conditions of the filter, the first one limits companies by the tenant of the request.
+func (f *CompanyFilter) conditions(ctx context.Context, qArgs *queryArgs) []string {
+	conditions := []string{tenantCondition(ctx, qArgs)}
+	if !f.WithDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if f.Country != "" {
+		conditions = append(conditions, "upper(country) = upper("+qArgs.add(f.Country)+")")
+	}
+	if f.CodePrefix != "" {
+		conditions = append(conditions, "code LIKE "+qArgs.add(escapeLike(f.CodePrefix)+"%"))
+	}
+	if f.NameContains != "" {
+		conditions = append(conditions, "name ILIKE "+qArgs.add("%"+escapeLike(f.NameContains)+"%"))
+	}
+	if f.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+qArgs.add(*f.CreatedFrom))
+	}
+	if f.CreatedTo != nil {
+		conditions = append(conditions, "created_at < "+qArgs.add(*f.CreatedTo))
+	}
+	if f.AddressCity != "" || f.AddressCountry != "" {
+		// both parts must match the same address
+		addressConditions := []string{"company_addresses.company_id = companies.id"}
+		if f.AddressCity != "" {
+			addressConditions = append(addressConditions, "lower(company_addresses.city) = lower("+qArgs.add(f.AddressCity)+")")
+		}
+		if f.AddressCountry != "" {
+			addressConditions = append(addressConditions, "company_addresses.country = upper("+qArgs.add(f.AddressCountry)+")")
+		}
+		conditions = append(conditions,
+			"EXISTS (SELECT 1 FROM company_addresses WHERE "+strings.Join(addressConditions, " AND ")+")",
+		)
+	}
+	if len(f.Tags) > 0 {
+		conditions = append(conditions, tagsCondition(qArgs, f.Tags, f.TagsMatch))
+	}
+
+	return conditions
+}
+
+// queryArgs collects arguments of the query built on the fly.
+type queryArgs struct {
+	args []any
+}
+
+// add appends the argument and returns its placeholder.
+func (qa *queryArgs) add(arg any) string {
+	qa.args = append(qa.args, arg)
+
+	return "$" + strconv.Itoa(len(qa.args))
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes the string to be matched literally in LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+// ErrEmptySearchQuery is returned when the search text has no words.
+var ErrEmptySearchQuery = errors.New("search query has no words")
+
+// SearchCompanies ranks companies by full-text match of name and code,
+// words are matched as prefixes and trigram similarity finds misspelled names.
+func SearchCompanies(
+	ctx context.Context, dbConn sqlx.QueryerContext, text string, limit int, withDeleted bool,
+) ([]Company, error) {
+	logger := logging.FromContext(ctx)
+	textQuery := prefixTSQuery(text)
+	if textQuery == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	query := `SELECT ` + companyColumns + `
+FROM companies, to_tsquery('simple', $1) AS text_query
+WHERE tenant_id = $5 AND ($4 OR deleted_at IS NULL)
+    AND (search_vector @@ text_query OR $2 <% name OR code % $2)
+ORDER BY ts_rank(search_vector, text_query) DESC,
+    greatest(word_similarity($2, name), similarity(code, $2)) DESC,
+    id
+LIMIT $3`
+	list := make([]Company, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, textQuery, text, limit, withDeleted, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).Error("search companies failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// prefixTSQuery turns "acme corp" into "acme:* & corp:*",
+// everything except letters and digits is dropped, so the result is always valid tsquery.
+func prefixTSQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i := range words {
+		words[i] = strings.ToLower(words[i]) + ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+// ErrCompanyAddressNotFound is returned when the address does not exist or belongs to another company.
+// It wraps sql.ErrNoRows, so it is handled as missing row as well.
+var ErrCompanyAddressNotFound = fmt.Errorf("company address not found: %w", sql.ErrNoRows)
+
+// ErrRegisteredAddressExists is returned when the company already has another registered address.
+var ErrRegisteredAddressExists = errors.New("company already has registered address")
+
+type AddressType string
+
+const (
+	AddressTypeRegistered  AddressType = "registered"
+	AddressTypeOperational AddressType = "operational"
+	AddressTypeBilling     AddressType = "billing"
+)
+
+// AddressTypes lists all address types in the order of the company_addresses_type_check constraint.
+var AddressTypes = []AddressType{
+	AddressTypeRegistered,
+	AddressTypeOperational,
+	AddressTypeBilling,
+}
+
+type CompanyAddress struct {
+	ID        uuid.UUID   `db:"id"`
+	CompanyID uuid.UUID   `db:"company_id"`
+	Type      AddressType `db:"type"`
+	Street    string      `db:"street"`
+	City      string      `db:"city"`
+	Postcode  string      `db:"postcode"`
+	Region    string      `db:"region"`
+	// Country is ISO 3166-1 alpha-2 code in upper case.
+	Country   string    `db:"country"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+const companyAddressColumns = `id, company_id, type, street, city, postcode, region, country, created_at, updated_at`
+
+// ListCompanyAddresses returns addresses of the company in the order of AddressTypes.
+func ListCompanyAddresses(
+	ctx context.Context, dbConn sqlx.QueryerContext, companyID uuid.UUID,
+) ([]CompanyAddress, error) {
+	logger := logging.FromContext(ctx)
+	query := `SELECT ` + companyAddressColumns + `
+FROM company_addresses
+WHERE company_id = $1
+ORDER BY array_position(ARRAY['registered', 'operational', 'billing']::varchar[], type), created_at, id`
+	list := make([]CompanyAddress, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, companyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("select company addresses failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// CreateCompanyAddress inserts the address, it must be called in the transaction locking the company.
+func CreateCompanyAddress(ctx context.Context, dbConn NamedExecQueryerContext, address *CompanyAddress) error {
+	logger := logging.FromContext(ctx).WithField("address_id", address.ID)
+	if err := checkRegisteredAddress(ctx, dbConn, address); err != nil {
+		return err
+	}
+	query := `INSERT INTO company_addresses (` + companyAddressColumns + `)
+VALUES (:id, :company_id, :type, :street, :city, :postcode, :region, :country, :created_at, :updated_at)`
+	_, err := dbConn.NamedExecContext(ctx, query, address)
+	if err != nil {
+		logger.WithError(err).Error("insert company address failed")
+
+		return translateAddressError(err)
+	}
+
+	return nil
+}
+
+// UpdateCompanyAddress saves the address, it must be called in the transaction locking the company.
+func UpdateCompanyAddress(ctx context.Context, dbConn NamedExecQueryerContext, address *CompanyAddress) error {
+	logger := logging.FromContext(ctx).WithField("address_id", address.ID)
+	if err := checkRegisteredAddress(ctx, dbConn, address); err != nil {
+		return err
+	}
+	query := `UPDATE company_addresses SET
+    type = :type, street = :street, city = :city, postcode = :postcode, region = :region, country = :country,
+    updated_at = :updated_at
+WHERE id = :id AND company_id = :company_id`
+	result, err := dbConn.NamedExecContext(ctx, query, address)
+	if err != nil {
+		logger.WithError(err).Error("update company address failed")
+
+		return translateAddressError(err)
+	}
+
+	return checkAddressAffected(ctx, result)
+}
+
+// GetCompanyAddress returns the address of the company.
+func GetCompanyAddress(
+	ctx context.Context, dbConn RowxQueryerContext, companyID, addressID uuid.UUID,
+) (*CompanyAddress, error) {
+	logger := logging.FromContext(ctx)
+	address := new(CompanyAddress)
+	query := `SELECT ` + companyAddressColumns + `
+FROM company_addresses
+WHERE id = $1 AND company_id = $2`
+	err := dbConn.QueryRowxContext(ctx, query, addressID, companyID).StructScan(address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCompanyAddressNotFound
+	}
+	if err != nil {
+		logger.WithError(err).WithField("address_id", addressID).Error("select company address failed")
+
+		return nil, err
+	}
+
+	return address, nil
+}
+
+func DeleteCompanyAddress(ctx context.Context, dbConn sqlx.ExecerContext, companyID, addressID uuid.UUID) error {
+	logger := logging.FromContext(ctx).WithField("address_id", addressID)
+	query := `DELETE FROM company_addresses WHERE id = $1 AND company_id = $2`
+	result, err := dbConn.ExecContext(ctx, query, addressID, companyID)
+	if err != nil {
+		logger.WithError(err).Error("delete company address failed")
+
+		return err
+	}
+
+	return checkAddressAffected(ctx, result)
+}
+
+// checkRegisteredAddress returns ErrRegisteredAddressExists if the registered address is added to the company
+// which already has another one.
+func checkRegisteredAddress(ctx context.Context, dbConn RowxQueryerContext, address *CompanyAddress) error {
+	if address.Type != AddressTypeRegistered {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+	var exists bool
+	query := `SELECT EXISTS (
+    SELECT 1 FROM company_addresses WHERE company_id = $1 AND type = $2 AND id <> $3
+)`
+	err := dbConn.QueryRowxContext(ctx, query, address.CompanyID, AddressTypeRegistered, address.ID).Scan(&exists)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", address.CompanyID).Error("check registered address failed")
+
+		return err
+	}
+	if exists {
+		return ErrRegisteredAddressExists
+	}
+
+	return nil
+}
+
+// translateAddressError turns unique violation of the registered address into ErrRegisteredAddressExists.
+func translateAddressError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode &&
+		pgErr.ConstraintName == "company_addresses_registered_key" {
+		return ErrRegisteredAddressExists
+	}
+
+	return err
+}
+
+func checkAddressAffected(ctx context.Context, result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("get affected rows failed")
+
+		return err
+	}
+	if affected == 0 {
+		return ErrCompanyAddressNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+type CompanyAuditAction string
+
+const (
+	CompanyAuditCreate  CompanyAuditAction = "create"
+	CompanyAuditUpdate  CompanyAuditAction = "update"
+	CompanyAuditDelete  CompanyAuditAction = "delete"
+	CompanyAuditRestore CompanyAuditAction = "restore"
+)
+
+// CompanyAuditRecord is one change of the company.
+type CompanyAuditRecord struct {
+	ID        int64              `db:"id"`
+	CompanyID uuid.UUID          `db:"company_id"`
+	Action    CompanyAuditAction `db:"action"`
+	Actor     string             `db:"actor"`
+	// RequestID is nil if the change was made outside of HTTP request.
+	RequestID *uuid.UUID `db:"request_id"`
+	// Before and After are JSON of the company, Before is nil on create.
+	Before    []byte    `db:"before"`
+	After     []byte    `db:"after"`
+	CreatedAt time.Time `db:"created_at"`
+	// TenantID is set from the context by InsertCompanyAudit.
+	TenantID string `db:"tenant_id"`
+}
+
+// InsertCompanyAudit appends the records to the history by one statement.
+func InsertCompanyAudit(ctx context.Context, dbConn NamedExerContext, records []CompanyAuditRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+	tenantID := TenantID(ctx)
+	for i := range records {
+		records[i].TenantID = tenantID
+	}
+	query := `INSERT INTO company_audit (
+    company_id, action, actor, request_id, before, after, created_at, tenant_id
+) VALUES (
+    :company_id, :action, :actor, :request_id, :before, :after, :created_at, :tenant_id
+)`
+	_, err := dbConn.NamedExecContext(ctx, query, records)
+	if err != nil {
+		logger.WithError(err).WithField("records_count", len(records)).Error("insert company audit failed")
+
+		return err
+	}
+
+	return nil
+}
+
+// ListCompanyAudit returns the page of history of the company of the tenant from the newest change,
+// beforeID is ID of the last record of the previous page, 0 for the first page.
+func ListCompanyAudit(
+	ctx context.Context, dbConn sqlx.QueryerContext, companyID uuid.UUID, beforeID int64, limit int,
+) ([]CompanyAuditRecord, error) {
+	logger := logging.FromContext(ctx)
+	query := `SELECT id, company_id, action, actor, request_id, before, after, created_at, tenant_id
+FROM company_audit
+WHERE company_id = $1 AND tenant_id = $4 AND ($2 = 0 OR id < $2)
+ORDER BY id DESC
+LIMIT $3`
+	list := make([]CompanyAuditRecord, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, companyID, beforeID, limit, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("select company audit failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
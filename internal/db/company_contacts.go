@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+// ErrCompanyContactNotFound is returned when the contact does not exist or belongs to another company.
+// It wraps sql.ErrNoRows, so it is handled as missing row as well.
+var ErrCompanyContactNotFound = fmt.Errorf("company contact not found: %w", sql.ErrNoRows)
+
+type ContactType string
+
+const (
+	ContactTypePhone ContactType = "phone"
+	ContactTypeEmail ContactType = "email"
+	ContactTypeFax   ContactType = "fax"
+)
+
+// ContactTypes lists all contact types in the order of the company_contacts_type_check constraint.
+var ContactTypes = []ContactType{
+	ContactTypePhone,
+	ContactTypeEmail,
+	ContactTypeFax,
+}
+
+type CompanyContact struct {
+	ID        uuid.UUID   `db:"id"`
+	CompanyID uuid.UUID   `db:"company_id"`
+	Type      ContactType `db:"type"`
+	Value     string      `db:"value"`
+	Label     string      `db:"label"`
+	// Name is the contact person.
+	Name string `db:"name"`
+	// Primary contact is the only one of its type for the company.
+	Primary   bool      `db:"is_primary"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+const companyContactColumns = `id, company_id, type, value, label, name, is_primary, created_at, updated_at`
+
+// ListCompanyContacts returns contacts of the company grouped by type, the primary contact goes first.
+func ListCompanyContacts(
+	ctx context.Context, dbConn sqlx.QueryerContext, companyID uuid.UUID,
+) ([]CompanyContact, error) {
+	logger := logging.FromContext(ctx)
+	query := `SELECT ` + companyContactColumns + `
+FROM company_contacts
+WHERE company_id = $1
+ORDER BY type, is_primary DESC, created_at, id`
+	list := make([]CompanyContact, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, companyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("select company contacts failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// CreateCompanyContact inserts the contact, the primary contact replaces the previous primary contact of its type.
+// It must be called in the transaction locking the company.
+func CreateCompanyContact(ctx context.Context, dbConn ExecNamedExerContext, contact *CompanyContact) error {
+	logger := logging.FromContext(ctx).WithField("contact_id", contact.ID)
+	if err := unsetPrimaryCompanyContact(ctx, dbConn, contact); err != nil {
+		return err
+	}
+	query := `INSERT INTO company_contacts (` + companyContactColumns + `)
+VALUES (:id, :company_id, :type, :value, :label, :name, :is_primary, :created_at, :updated_at)`
+	_, err := dbConn.NamedExecContext(ctx, query, contact)
+	if err != nil {
+		logger.WithError(err).Error("insert company contact failed")
+
+		return err
+	}
+
+	return nil
+}
+
+// UpdateCompanyContact saves the contact, the primary contact replaces the previous primary contact of its type.
+// It must be called in the transaction locking the company.
+func UpdateCompanyContact(ctx context.Context, dbConn ExecNamedExerContext, contact *CompanyContact) error {
+	logger := logging.FromContext(ctx).WithField("contact_id", contact.ID)
+	if err := unsetPrimaryCompanyContact(ctx, dbConn, contact); err != nil {
+		return err
+	}
+	query := `UPDATE company_contacts SET
+    type = :type, value = :value, label = :label, name = :name, is_primary = :is_primary, updated_at = :updated_at
+WHERE id = :id AND company_id = :company_id`
+	result, err := dbConn.NamedExecContext(ctx, query, contact)
+	if err != nil {
+		logger.WithError(err).Error("update company contact failed")
+
+		return err
+	}
+
+	return checkContactAffected(ctx, result)
+}
+
+// GetCompanyContact returns the contact of the company.
+func GetCompanyContact(
+	ctx context.Context, dbConn RowxQueryerContext, companyID, contactID uuid.UUID,
+) (*CompanyContact, error) {
+	logger := logging.FromContext(ctx)
+	contact := new(CompanyContact)
+	query := `SELECT ` + companyContactColumns + `
+FROM company_contacts
+WHERE id = $1 AND company_id = $2`
+	err := dbConn.QueryRowxContext(ctx, query, contactID, companyID).StructScan(contact)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCompanyContactNotFound
+	}
+	if err != nil {
+		logger.WithError(err).WithField("contact_id", contactID).Error("select company contact failed")
+
+		return nil, err
+	}
+
+	return contact, nil
+}
+
+func DeleteCompanyContact(ctx context.Context, dbConn sqlx.ExecerContext, companyID, contactID uuid.UUID) error {
+	logger := logging.FromContext(ctx).WithField("contact_id", contactID)
+	query := `DELETE FROM company_contacts WHERE id = $1 AND company_id = $2`
+	result, err := dbConn.ExecContext(ctx, query, contactID, companyID)
+	if err != nil {
+		logger.WithError(err).Error("delete company contact failed")
+
+		return err
+	}
+
+	return checkContactAffected(ctx, result)
+}
+
+func unsetPrimaryCompanyContact(ctx context.Context, dbConn sqlx.ExecerContext, contact *CompanyContact) error {
+	if !contact.Primary {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+	query := `UPDATE company_contacts SET is_primary = false, updated_at = $4
+WHERE company_id = $1 AND type = $2 AND is_primary AND id <> $3`
+	_, err := dbConn.ExecContext(ctx, query, contact.CompanyID, contact.Type, contact.ID, contact.UpdatedAt)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", contact.CompanyID).Error("unset primary company contact failed")
+
+		return err
+	}
+
+	return nil
+}
+
+func checkContactAffected(ctx context.Context, result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("get affected rows failed")
+
+		return err
+	}
+	if affected == 0 {
+		return ErrCompanyContactNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+var (
+	// ErrParentCompanyNotFound is returned when the new parent does not exist or is deleted.
+	ErrParentCompanyNotFound = errors.New("parent company not found")
+	// ErrCompanyHierarchyCycle is returned when the new parent is the company itself or its subsidiary.
+	ErrCompanyHierarchyCycle = errors.New("parent company is the company or its subsidiary")
+)
+
+// companyHierarchyLockID is the key of the advisory locks serializing changes of parents,
+// so concurrent changes can not make a cycle checked by each of them separately.
+// It is paired with the hash of the tenant, hierarchies of tenants are changed independently.
+const companyHierarchyLockID = 7302
+
+// SetCompanyParent saves ParentID of the company if its version in the database is still dbCompany.Version.
+// The parent must be not deleted company of the same tenant which is not the subsidiary of the company.
+func SetCompanyParent(ctx context.Context, dbConn ExecQueryerContext, dbCompany *Company) error {
+	logger := logging.FromContext(ctx).WithField("company_id", dbCompany.ID)
+	lockQuery := `SELECT pg_advisory_xact_lock($1, hashtext($2))`
+	if _, err := dbConn.ExecContext(ctx, lockQuery, companyHierarchyLockID, TenantID(ctx)); err != nil {
+		logger.WithError(err).Error("lock company hierarchy failed")
+
+		return err
+	}
+	if dbCompany.ParentID != nil {
+		if err := checkCompanyParent(ctx, dbConn, dbCompany.ID, *dbCompany.ParentID); err != nil {
+			return err
+		}
+	}
+	query := `UPDATE companies SET parent_id = $1, updated_at = $2, updated_by = $3, version = version + 1
+WHERE id = $4 AND tenant_id = $5 AND version = $6 AND deleted_at IS NULL`
+	result, err := dbConn.ExecContext(ctx, query,
+		dbCompany.ParentID, dbCompany.UpdatedAt, dbCompany.UpdatedBy, dbCompany.ID, TenantID(ctx), dbCompany.Version,
+	)
+	if err != nil {
+		logger.WithError(err).Error("update company parent failed")
+
+		return err
+	}
+	if err = checkVersionedWrite(ctx, dbConn, dbCompany.ID, result); err != nil {
+		return err
+	}
+	dbCompany.Version++
+
+	return nil
+}
+
+// checkCompanyParent walks up from the parent including deleted companies, they keep parents and can be restored.
+func checkCompanyParent(ctx context.Context, dbConn RowxQueryerContext, companyID, parentID uuid.UUID) error {
+	logger := logging.FromContext(ctx).WithField("company_id", companyID)
+	var parentExists, isCycle bool
+	query := `WITH RECURSIVE ancestors (ancestor_id) AS (
+    SELECT $1::uuid
+    UNION ALL
+    SELECT companies.parent_id
+    FROM ancestors JOIN companies ON companies.id = ancestors.ancestor_id
+    WHERE companies.parent_id IS NOT NULL
+) CYCLE ancestor_id SET is_cycle USING path
+SELECT
+    EXISTS (SELECT 1 FROM companies WHERE id = $1 AND tenant_id = $3 AND deleted_at IS NULL),
+    EXISTS (SELECT 1 FROM ancestors WHERE ancestor_id = $2)`
+	err := dbConn.QueryRowxContext(ctx, query, parentID, companyID, TenantID(ctx)).Scan(&parentExists, &isCycle)
+	if err != nil {
+		logger.WithError(err).WithField("parent_id", parentID).Error("check company parent failed")
+
+		return err
+	}
+	switch {
+	case !parentExists:
+		return ErrParentCompanyNotFound
+	case isCycle:
+		return ErrCompanyHierarchyCycle
+	}
+
+	return nil
+}
+
+// ListCompanyChildren returns not deleted direct subsidiaries of the company ordered by name.
+func ListCompanyChildren(ctx context.Context, dbConn sqlx.QueryerContext, companyID uuid.UUID) ([]Company, error) {
+	logger := logging.FromContext(ctx)
+	query := `SELECT ` + companyColumns + `
+FROM companies
+WHERE parent_id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+ORDER BY name, id`
+	list := make([]Company, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, companyID, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("select company children failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListCompanyAncestors returns parents of the company from the direct parent to the top level company.
+// The chain ends at the deleted parent.
+func ListCompanyAncestors(ctx context.Context, dbConn sqlx.QueryerContext, companyID uuid.UUID) ([]Company, error) {
+	logger := logging.FromContext(ctx)
+	query := `WITH RECURSIVE ancestors (ancestor_id, depth) AS (
+    SELECT parent_id, 1 FROM companies WHERE id = $1 AND tenant_id = $2 AND parent_id IS NOT NULL
+    UNION ALL
+    SELECT companies.parent_id, ancestors.depth + 1
+    FROM ancestors JOIN companies ON companies.id = ancestors.ancestor_id
+    WHERE companies.parent_id IS NOT NULL AND companies.deleted_at IS NULL
+) CYCLE ancestor_id SET is_cycle USING path
+SELECT ` + companyColumns + `
+FROM companies JOIN ancestors ON companies.id = ancestors.ancestor_id
+WHERE NOT is_cycle AND tenant_id = $2 AND deleted_at IS NULL
+ORDER BY depth`
+	list := make([]Company, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, companyID, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("select company ancestors failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListCompanyDescendants returns the not deleted company and all its subsidiaries level by level,
+// companies of the same level are ordered by name. Subsidiaries of the deleted company are skipped.
+func ListCompanyDescendants(ctx context.Context, dbConn sqlx.QueryerContext, companyID uuid.UUID) ([]Company, error) {
+	logger := logging.FromContext(ctx)
+	query := `WITH RECURSIVE descendants (descendant_id, depth) AS (
+    SELECT id, 0 FROM companies WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL
+    UNION ALL
+    SELECT companies.id, descendants.depth + 1
+    FROM descendants JOIN companies ON companies.parent_id = descendants.descendant_id
+    WHERE companies.deleted_at IS NULL
+) CYCLE descendant_id SET is_cycle USING path
+SELECT ` + companyColumns + `
+FROM companies JOIN descendants ON companies.id = descendants.descendant_id
+WHERE NOT is_cycle AND tenant_id = $2
+ORDER BY depth, name, id`
+	list := make([]Company, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, companyID, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("select company descendants failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
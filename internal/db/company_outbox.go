@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+type CompanyEventType string
+
+const (
+	CompanyCreated  CompanyEventType = "CompanyCreated"
+	CompanyUpdated  CompanyEventType = "CompanyUpdated"
+	CompanyDeleted  CompanyEventType = "CompanyDeleted"
+	CompanyRestored CompanyEventType = "CompanyRestored"
+)
+
+// CompanyEventTypesList lists all company event types.
+var CompanyEventTypesList = []CompanyEventType{
+	CompanyCreated,
+	CompanyUpdated,
+	CompanyDeleted,
+	CompanyRestored,
+}
+
+// CompanyEvent is the lifecycle event of the company waiting in the outbox to be published.
+type CompanyEvent struct {
+	ID        int64            `db:"id"`
+	Type      CompanyEventType `db:"type"`
+	CompanyID uuid.UUID        `db:"company_id"`
+	// Payload is JSON of the company after the change.
+	Payload   []byte    `db:"payload"`
+	CreatedAt time.Time `db:"created_at"`
+	// DeliveredAt is nil until the event is published.
+	DeliveredAt *time.Time `db:"delivered_at"`
+	Attempts    int        `db:"attempts"`
+	LastError   string     `db:"last_error"`
+	// TenantID is the tenant of the company, it is set from the context by InsertCompanyEvents.
+	TenantID string `db:"tenant_id"`
+}
+
+// companyOutboxLockID is the key of the advisory locks serializing writers of the outbox,
+// it is paired with the hash of the tenant.
+const companyOutboxLockID = 7301
+
+// InsertCompanyEvents puts the events into the outbox by one statement,
+// it must be called in the transaction of the change.
+// Writers of the outbox of one tenant are serialized until the end of their transactions, so events of the tenant
+// are committed in the order of their IDs and readers of the tenant never see the event before the one
+// with the lower ID. Writers of different tenants do not wait for each other.
+func InsertCompanyEvents(ctx context.Context, dbConn ExecNamedExerContext, events []CompanyEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+	tenantID := TenantID(ctx)
+	lockQuery := `SELECT pg_advisory_xact_lock($1, hashtext($2))`
+	if _, err := dbConn.ExecContext(ctx, lockQuery, companyOutboxLockID, tenantID); err != nil {
+		logger.WithError(err).Error("lock company outbox failed")
+
+		return err
+	}
+	for i := range events {
+		events[i].TenantID = tenantID
+	}
+	query := `INSERT INTO company_outbox (type, company_id, payload, created_at, tenant_id)
+VALUES (:type, :company_id, :payload, :created_at, :tenant_id)`
+	_, err := dbConn.NamedExecContext(ctx, query, events)
+	if err != nil {
+		logger.WithError(err).WithField("events_count", len(events)).Error("insert company events failed")
+
+		return err
+	}
+
+	return nil
+}
+
+// LockPendingCompanyEvents returns the oldest not delivered events and locks them until the end of the transaction,
+// events locked by another transaction are skipped.
+func LockPendingCompanyEvents(ctx context.Context, dbConn sqlx.QueryerContext, limit int) ([]CompanyEvent, error) {
+	logger := logging.FromContext(ctx)
+	query := `SELECT id, type, company_id, payload, created_at, delivered_at, attempts, last_error, tenant_id
+FROM company_outbox
+WHERE delivered_at IS NULL
+ORDER BY id
+LIMIT $1
+FOR UPDATE SKIP LOCKED`
+	list := make([]CompanyEvent, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, limit)
+	if err != nil {
+		logger.WithError(err).Error("select pending company events failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// MarkCompanyEventsDelivered marks the events as published.
+func MarkCompanyEventsDelivered(
+	ctx context.Context, dbConn sqlx.ExecerContext, eventIDs []int64, deliveredAt time.Time,
+) error {
+	if len(eventIDs) == 0 {
+		return nil
+	}
+	logger := logging.FromContext(ctx)
+	qArgs := new(queryArgs)
+	deliveredAtArg := qArgs.add(deliveredAt)
+	placeholders := make([]string, 0, len(eventIDs))
+	for _, eventID := range eventIDs {
+		placeholders = append(placeholders, qArgs.add(eventID))
+	}
+	query := `UPDATE company_outbox SET delivered_at = ` + deliveredAtArg + `, attempts = attempts + 1, last_error = ''
+WHERE id IN (` + strings.Join(placeholders, ", ") + `)`
+	_, err := dbConn.ExecContext(ctx, query, qArgs.args...)
+	if err != nil {
+		logger.WithError(err).WithField("events_count", len(eventIDs)).Error("mark company events delivered failed")
+
+		return err
+	}
+
+	return nil
+}
+
+// MarkCompanyEventFailed records failed attempt to publish the event.
+func MarkCompanyEventFailed(ctx context.Context, dbConn sqlx.ExecerContext, eventID int64, publishErr error) error {
+	logger := logging.FromContext(ctx)
+	query := `UPDATE company_outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1`
+	_, err := dbConn.ExecContext(ctx, query, eventID, publishErr.Error())
+	if err != nil {
+		logger.WithError(err).WithField("event_id", eventID).Error("update company event attempts failed")
+
+		return err
+	}
+
+	return nil
+}
+
+// ListCompanyEvents returns events of the tenant with ID greater than afterID in the order of IDs,
+// delivered or not.
+func ListCompanyEvents(
+	ctx context.Context, dbConn sqlx.QueryerContext, afterID int64, limit int,
+) ([]CompanyEvent, error) {
+	logger := logging.FromContext(ctx)
+	query := `SELECT id, type, company_id, payload, created_at, delivered_at, attempts, last_error, tenant_id
+FROM company_outbox
+WHERE id > $1 AND tenant_id = $3
+ORDER BY id
+LIMIT $2`
+	list := make([]CompanyEvent, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, afterID, limit, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).WithField("after_id", afterID).Error("select company events failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// GetLastCompanyEventID returns ID of the newest event of the tenant, 0 if there are no events.
+func GetLastCompanyEventID(ctx context.Context, dbConn RowxQueryerContext) (int64, error) {
+	logger := logging.FromContext(ctx)
+	var lastID int64
+	query := `SELECT COALESCE(MAX(id), 0) FROM company_outbox WHERE tenant_id = $1`
+	err := dbConn.QueryRowxContext(ctx, query, TenantID(ctx)).Scan(&lastID)
+	if err != nil {
+		logger.WithError(err).Error("select last company event id failed")
+
+		return 0, err
+	}
+
+	return lastID, nil
+}
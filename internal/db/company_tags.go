@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+// companyTagsColumn selects names of the company tags as JSON array, companies table must not be aliased.
+const companyTagsColumn = `COALESCE((
+        SELECT jsonb_agg(tags.name ORDER BY tags.name)
+        FROM company_tags JOIN tags ON tags.id = company_tags.tag_id
+        WHERE company_tags.company_id = companies.id
+    ), '[]') AS tags`
+
+// TagNames are names of the company tags in alphabetical order.
+type TagNames []string
+
+// Scan reads the JSON array of companyTagsColumn.
+func (tn *TagNames) Scan(src any) error {
+	var data []byte
+	switch value := src.(type) {
+	case nil:
+		*tn = TagNames{}
+
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return fmt.Errorf("unsupported type of tags: %T", src)
+	}
+	names := make([]string, 0)
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("decode tags failed: %w", err)
+	}
+	*tn = names
+
+	return nil
+}
+
+// TagsMatch tells if the company must have any or all tags of the filter.
+type TagsMatch string
+
+const (
+	TagsMatchAny TagsMatch = "any"
+	TagsMatchAll TagsMatch = "all"
+)
+
+type Tag struct {
+	ID        uuid.UUID `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	// CompaniesCount is the number of not deleted companies having the tag.
+	CompaniesCount int `db:"companies_count"`
+}
+
+// ListTags returns all tags of the tenant ordered by name.
+func ListTags(ctx context.Context, dbConn sqlx.QueryerContext) ([]Tag, error) {
+	logger := logging.FromContext(ctx)
+	query := `SELECT tags.id, tags.name, tags.created_at, count(companies.id) AS companies_count
+FROM tags
+    LEFT JOIN company_tags ON company_tags.tag_id = tags.id
+    LEFT JOIN companies ON companies.id = company_tags.company_id AND companies.deleted_at IS NULL
+WHERE tags.tenant_id = $1
+GROUP BY tags.id
+ORDER BY tags.name`
+	list := make([]Tag, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).Error("select tags failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// AttachCompanyTags creates missing tags of the tenant and attaches them to the company, attached tags are skipped.
+// tagIDs are IDs of tags to create, they are generated by the caller, one per name.
+// It returns the number of attached tags and must be called in the transaction locking the company.
+func AttachCompanyTags(
+	ctx context.Context, dbConn sqlx.ExecerContext, companyID uuid.UUID, names []string, tagIDs []uuid.UUID,
+	attachedAt time.Time,
+) (int64, error) {
+	logger := logging.FromContext(ctx).WithField("company_id", companyID)
+	if len(names) == 0 {
+		return 0, nil
+	}
+	tenantID := TenantID(ctx)
+	for i, name := range names {
+		query := `INSERT INTO tags (id, name, created_at, tenant_id) VALUES ($1, $2, $3, $4)
+ON CONFLICT ON CONSTRAINT tags_name_key DO NOTHING`
+		if _, err := dbConn.ExecContext(ctx, query, tagIDs[i], name, attachedAt, tenantID); err != nil {
+			logger.WithError(err).WithField("tag", name).Error("insert tag failed")
+
+			return 0, err
+		}
+	}
+
+	qArgs := new(queryArgs)
+	placeholders := make([]string, 0, len(names))
+	for _, name := range names {
+		placeholders = append(placeholders, qArgs.add(name))
+	}
+	query := `INSERT INTO company_tags (company_id, tag_id, created_at)
+SELECT ` + qArgs.add(companyID) + `, id, ` + qArgs.add(attachedAt) + `
+FROM tags
+WHERE tenant_id = ` + qArgs.add(tenantID) + ` AND name IN (` + strings.Join(placeholders, ", ") + `)
+ON CONFLICT DO NOTHING`
+	result, err := dbConn.ExecContext(ctx, query, qArgs.args...)
+	if err != nil {
+		logger.WithError(err).Error("attach company tags failed")
+
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DetachCompanyTag removes the tag from the company, detaching not attached tag succeeds.
+// It returns the number of detached tags, 0 or 1.
+func DetachCompanyTag(
+	ctx context.Context, dbConn sqlx.ExecerContext, companyID uuid.UUID, name string,
+) (int64, error) {
+	logger := logging.FromContext(ctx).WithField("company_id", companyID)
+	query := `DELETE FROM company_tags
+USING tags
+WHERE company_tags.tag_id = tags.id AND company_tags.company_id = $1 AND tags.name = $2 AND tags.tenant_id = $3`
+	result, err := dbConn.ExecContext(ctx, query, companyID, name, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).WithField("tag", name).Error("detach company tag failed")
+
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// TouchCompany saves new version of the company changed by its tags.
+func TouchCompany(ctx context.Context, dbConn ExecQueryerContext, dbCompany *Company) error {
+	logger := logging.FromContext(ctx).WithField("company_id", dbCompany.ID)
+	query := `UPDATE companies SET updated_at = $1, updated_by = $2, version = version + 1
+WHERE id = $3 AND tenant_id = $4 AND version = $5 AND deleted_at IS NULL`
+	result, err := dbConn.ExecContext(ctx, query,
+		dbCompany.UpdatedAt, dbCompany.UpdatedBy, dbCompany.ID, TenantID(ctx), dbCompany.Version,
+	)
+	if err != nil {
+		logger.WithError(err).Error("touch company failed")
+
+		return err
+	}
+	if err = checkVersionedWrite(ctx, dbConn, dbCompany.ID, result); err != nil {
+		return err
+	}
+	dbCompany.Version++
+
+	return nil
+}
+
+// tagsCondition returns SQL condition matching companies having any or all of the tags.
+func tagsCondition(qArgs *queryArgs, names []string, match TagsMatch) string {
+	placeholders := make([]string, 0, len(names))
+	for _, name := range names {
+		placeholders = append(placeholders, qArgs.add(name))
+	}
+	from := `FROM company_tags JOIN tags ON tags.id = company_tags.tag_id
+WHERE company_tags.company_id = companies.id AND tags.name IN (` + strings.Join(placeholders, ", ") + `)`
+	if match == TagsMatchAll {
+		// names are unique, so the company has all tags if every name is matched
+		return "(SELECT count(*) " + from + ") = " + qArgs.add(len(names))
+	}
+
+	return "EXISTS (SELECT 1 " + from + ")"
+}
@@ -26,3 +26,28 @@ func Connect(ctx context.Context, dbConf *config.DB) (*sqlx.DB, error) {
 func Disconnect(dbConn *sqlx.DB) error {
 	return dbConn.Close()
 }
+
+// WithTx runs fn in the transaction, the transaction is committed if fn succeeds and rolled back otherwise.
+func WithTx(ctx context.Context, dbConn *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	logger := logging.FromContext(ctx)
+	tx, err := dbConn.BeginTxx(ctx, nil)
+	if err != nil {
+		logger.WithError(err).Error("begin transaction failed")
+
+		return err
+	}
+	if err = fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logger.WithError(rollbackErr).Error("rollback transaction failed")
+		}
+
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.WithError(err).Error("commit transaction failed")
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+// ErrIdempotencyKeyNotFound is returned when the key was never saved or is expired.
+// It wraps sql.ErrNoRows, so it is handled as missing row as well.
+var ErrIdempotencyKeyNotFound = fmt.Errorf("idempotency key not found: %w", sql.ErrNoRows)
+
+// ErrIdempotencyKeyExists is returned when not expired key was saved concurrently.
+var ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+
+// IdempotencyKey keeps the response of the request to replay it on retry.
+type IdempotencyKey struct {
+	Key            string    `db:"key"`
+	RequestHash    string    `db:"request_hash"`
+	ResponseStatus int       `db:"response_status"`
+	ResponseBody   []byte    `db:"response_body"`
+	ResponseETag   string    `db:"response_etag"`
+	CreatedAt      time.Time `db:"created_at"`
+	ExpiresAt      time.Time `db:"expires_at"`
+	// TenantID is set from the context by SaveIdempotencyKey, keys of different tenants do not collide.
+	TenantID string `db:"tenant_id"`
+	// Actor is the client which saved the key, keys of different clients do not collide.
+	Actor string `db:"actor"`
+}
+
+// GetIdempotencyKey returns the key of the tenant and the actor which is not expired at now.
+func GetIdempotencyKey(
+	ctx context.Context, dbConn RowxQueryerContext, key, actor string, now time.Time,
+) (*IdempotencyKey, error) {
+	logger := logging.FromContext(ctx)
+	idempotencyKey := new(IdempotencyKey)
+	query := `SELECT key, request_hash, response_status, response_body, response_etag, created_at, expires_at,
+    tenant_id, actor
+FROM idempotency_keys
+WHERE tenant_id = $1 AND actor = $2 AND key = $3 AND expires_at > $4`
+	err := dbConn.QueryRowxContext(ctx, query, TenantID(ctx), actor, key, now).StructScan(idempotencyKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		logger.WithError(err).WithField("idempotency_key", key).Error("select idempotency key failed")
+
+		return nil, err
+	}
+
+	return idempotencyKey, nil
+}
+
+// SaveIdempotencyKey saves the key replacing the expired one.
+// ErrIdempotencyKeyExists is returned if the key is saved and not expired yet.
+func SaveIdempotencyKey(ctx context.Context, dbConn NamedExerContext, idempotencyKey *IdempotencyKey) error {
+	logger := logging.FromContext(ctx)
+	idempotencyKey.TenantID = TenantID(ctx)
+	query := `INSERT INTO idempotency_keys (
+    key, request_hash, response_status, response_body, response_etag, created_at, expires_at, tenant_id, actor
+) VALUES (
+    :key, :request_hash, :response_status, :response_body, :response_etag, :created_at, :expires_at, :tenant_id, :actor
+)
+ON CONFLICT (tenant_id, actor, key) DO UPDATE SET
+    request_hash = excluded.request_hash, response_status = excluded.response_status,
+    response_body = excluded.response_body, response_etag = excluded.response_etag,
+    created_at = excluded.created_at, expires_at = excluded.expires_at
+WHERE idempotency_keys.expires_at <= excluded.created_at`
+	result, err := dbConn.NamedExecContext(ctx, query, idempotencyKey)
+	if err != nil {
+		logger.WithError(err).WithField("idempotency_key", idempotencyKey.Key).Error("save idempotency key failed")
+
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.WithError(err).WithField("idempotency_key", idempotencyKey.Key).Error("get affected rows failed")
+
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrIdempotencyKeyExists
+	}
+
+	return nil
+}
+
+// PurgeExpiredIdempotencyKeys removes keys expired before now.
+func PurgeExpiredIdempotencyKeys(ctx context.Context, dbConn sqlx.ExecerContext, now time.Time) (int64, error) {
+	logger := logging.FromContext(ctx)
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= $1`
+	result, err := dbConn.ExecContext(ctx, query, now)
+	if err != nil {
+		logger.WithError(err).Error("purge idempotency keys failed")
+
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		logger.WithError(err).Error("get affected rows failed")
+
+		return 0, err
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,28 @@
+package db
+
+import "context"
+
+// DefaultTenantID is the tenant of anonymous clients, of clients without tenant and of data created before tenants.
+const DefaultTenantID = "default"
+
+type tenantIDCtxKey struct{}
+
+// WithTenantID stores the tenant of the request in the context, all queries are limited by the tenant.
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDCtxKey{}, tenantID)
+}
+
+// TenantID returns the tenant of the request, DefaultTenantID if it was not set.
+func TenantID(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantIDCtxKey{}).(string)
+	if tenantID == "" {
+		return DefaultTenantID
+	}
+
+	return tenantID
+}
+
+// tenantCondition returns SQL condition selecting rows of the tenant of the request.
+func tenantCondition(ctx context.Context, qArgs *queryArgs) string {
+	return "tenant_id = " + qArgs.add(TenantID(ctx))
+}
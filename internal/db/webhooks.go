@@ -0,0 +1,312 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+)
+
+// ErrWebhookNotFound is returned when the webhook subscription does not exist.
+// It wraps sql.ErrNoRows, so it is handled as missing row as well.
+var ErrWebhookNotFound = fmt.Errorf("webhook not found: %w", sql.ErrNoRows)
+
+// CompanyEventTypes is stored as JSON array.
+type CompanyEventTypes []CompanyEventType
+
+func (t CompanyEventTypes) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
+
+func (t *CompanyEventTypes) Scan(src any) error {
+	switch value := src.(type) {
+	case []byte:
+		return json.Unmarshal(value, t)
+	case string:
+		return json.Unmarshal([]byte(value), t)
+	default:
+		return fmt.Errorf("unsupported type of company event types: %T", src)
+	}
+}
+
+type WebhookSubscription struct {
+	ID         uuid.UUID         `db:"id"`
+	URL        string            `db:"url"`
+	EventTypes CompanyEventTypes `db:"event_types"`
+	// Secret signs payloads by HMAC-SHA256.
+	Secret string `db:"secret"`
+	// Owner is the actor of the client registered the webhook.
+	Owner     string    `db:"owner"`
+	CreatedAt time.Time `db:"created_at"`
+	// TenantID is set from the context by CreateWebhookSubscription, the webhook gets events of the tenant only.
+	TenantID string `db:"tenant_id"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryDead is the delivery which is not retried anymore.
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery is the company event to deliver to the webhook.
+type WebhookDelivery struct {
+	ID             int64                 `db:"id"`
+	SubscriptionID uuid.UUID             `db:"subscription_id"`
+	EventID        int64                 `db:"event_id"`
+	EventType      CompanyEventType      `db:"event_type"`
+	Payload        []byte                `db:"payload"`
+	Status         WebhookDeliveryStatus `db:"status"`
+	Attempts       int                   `db:"attempts"`
+	NextAttemptAt  time.Time             `db:"next_attempt_at"`
+	LastError      string                `db:"last_error"`
+	CreatedAt      time.Time             `db:"created_at"`
+	DeliveredAt    *time.Time            `db:"delivered_at"`
+}
+
+// DueWebhookDelivery is the delivery with the webhook it goes to.
+type DueWebhookDelivery struct {
+	WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID          int64     `db:"id"`
+	DeliveryID  int64     `db:"delivery_id"`
+	AttemptedAt time.Time `db:"attempted_at"`
+	// StatusCode is 0 if there is no response.
+	StatusCode int    `db:"status_code"`
+	Error      string `db:"error"`
+	DurationMs int64  `db:"duration_ms"`
+}
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts,
+    next_attempt_at, last_error, created_at, delivered_at`
+
+func CreateWebhookSubscription(ctx context.Context, dbConn NamedExerContext, subscription *WebhookSubscription) error {
+	logger := logging.FromContext(ctx)
+	subscription.TenantID = TenantID(ctx)
+	query := `INSERT INTO webhook_subscriptions (id, url, event_types, secret, owner, created_at, tenant_id)
+VALUES (:id, :url, :event_types, :secret, :owner, :created_at, :tenant_id)`
+	_, err := dbConn.NamedExecContext(ctx, query, subscription)
+	if err != nil {
+		logger.WithError(err).WithField("webhook_id", subscription.ID).Error("insert webhook failed")
+
+		return err
+	}
+
+	return nil
+}
+
+func GetWebhookSubscription(
+	ctx context.Context, dbConn RowxQueryerContext, subscriptionID uuid.UUID,
+) (*WebhookSubscription, error) {
+	logger := logging.FromContext(ctx)
+	subscription := new(WebhookSubscription)
+	query := `SELECT id, url, event_types, secret, owner, created_at, tenant_id
+FROM webhook_subscriptions
+WHERE id = $1 AND tenant_id = $2`
+	err := dbConn.QueryRowxContext(ctx, query, subscriptionID, TenantID(ctx)).StructScan(subscription)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		logger.WithError(err).WithField("webhook_id", subscriptionID).Error("select webhook failed")
+
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// ListWebhookSubscriptions returns webhooks of the tenant in the order they were registered,
+// only webhooks of the owner are returned unless owner is empty.
+func ListWebhookSubscriptions(
+	ctx context.Context, dbConn sqlx.QueryerContext, owner string,
+) ([]WebhookSubscription, error) {
+	logger := logging.FromContext(ctx)
+	query := `SELECT id, url, event_types, secret, owner, created_at, tenant_id
+FROM webhook_subscriptions
+WHERE tenant_id = $1 AND ($2::text = '' OR owner = $2)
+ORDER BY created_at, id`
+	list := make([]WebhookSubscription, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, TenantID(ctx), owner)
+	if err != nil {
+		logger.WithError(err).Error("select webhooks failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// DeleteWebhookSubscription deletes the webhook with all its deliveries.
+func DeleteWebhookSubscription(ctx context.Context, dbConn sqlx.ExecerContext, subscriptionID uuid.UUID) error {
+	logger := logging.FromContext(ctx).WithField("webhook_id", subscriptionID)
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1 AND tenant_id = $2`
+	result, err := dbConn.ExecContext(ctx, query, subscriptionID, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).Error("delete webhook failed")
+
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		logger.WithError(err).Error("get affected rows failed")
+
+		return err
+	}
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// CreateWebhookDeliveries schedules delivery of the event to all webhooks of the tenant subscribed to its type.
+// The event is scheduled for the webhook only once, so it is safe to call it again for the same event.
+func CreateWebhookDeliveries(
+	ctx context.Context, dbConn sqlx.ExecerContext,
+	eventID int64, eventType CompanyEventType, payload []byte, createdAt time.Time,
+) (int64, error) {
+	logger := logging.FromContext(ctx)
+	query := `INSERT INTO webhook_deliveries (
+    subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at
+)
+SELECT id, $1, $2::text, $3, 'pending', $4, $4
+FROM webhook_subscriptions
+WHERE tenant_id = $5 AND event_types @> jsonb_build_array($2::text)
+ON CONFLICT (subscription_id, event_id) DO NOTHING`
+	result, err := dbConn.ExecContext(ctx, query, eventID, eventType, payload, createdAt, TenantID(ctx))
+	if err != nil {
+		logger.WithError(err).WithField("event_id", eventID).Error("insert webhook deliveries failed")
+
+		return 0, err
+	}
+	created, err := result.RowsAffected()
+	if err != nil {
+		logger.WithError(err).Error("get affected rows failed")
+
+		return 0, err
+	}
+
+	return created, nil
+}
+
+// ClaimDueWebhookDeliveries returns pending deliveries which are due at now and postpones them until leaseUntil,
+// so other dispatchers skip them while they are delivered. The claim is committed at once, the delivery which
+// is not saved before leaseUntil (the dispatcher died) is claimed again.
+func ClaimDueWebhookDeliveries(
+	ctx context.Context, dbConn sqlx.QueryerContext, now, leaseUntil time.Time, limit int,
+) ([]DueWebhookDelivery, error) {
+	logger := logging.FromContext(ctx)
+	query := `UPDATE webhook_deliveries d SET next_attempt_at = $2
+FROM webhook_subscriptions s
+WHERE s.id = d.subscription_id AND d.id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= $1
+    ORDER BY next_attempt_at, id
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+    d.next_attempt_at, d.last_error, d.created_at, d.delivered_at, s.url, s.secret`
+	list := make([]DueWebhookDelivery, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, now, leaseUntil, limit)
+	if err != nil {
+		logger.WithError(err).Error("claim due webhook deliveries failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// SaveWebhookDeliveryAttempt records the attempt and saves the state of the delivery after it.
+func SaveWebhookDeliveryAttempt(
+	ctx context.Context, dbConn NamedExerContext, delivery *WebhookDelivery, attempt *WebhookDeliveryAttempt,
+) error {
+	logger := logging.FromContext(ctx).WithField("delivery_id", delivery.ID)
+	query := `INSERT INTO webhook_delivery_attempts (delivery_id, attempted_at, status_code, error, duration_ms)
+VALUES (:delivery_id, :attempted_at, :status_code, :error, :duration_ms)`
+	_, err := dbConn.NamedExecContext(ctx, query, attempt)
+	if err != nil {
+		logger.WithError(err).Error("insert webhook delivery attempt failed")
+
+		return err
+	}
+	query = `UPDATE webhook_deliveries SET
+    status = :status, attempts = :attempts, next_attempt_at = :next_attempt_at,
+    last_error = :last_error, delivered_at = :delivered_at
+WHERE id = :id`
+	_, err = dbConn.NamedExecContext(ctx, query, delivery)
+	if err != nil {
+		logger.WithError(err).Error("update webhook delivery failed")
+
+		return err
+	}
+
+	return nil
+}
+
+// ListWebhookDeliveries returns the page of deliveries of the webhook from the newest one,
+// beforeID is ID of the last delivery of the previous page, 0 for the first page.
+func ListWebhookDeliveries(
+	ctx context.Context, dbConn sqlx.QueryerContext, subscriptionID uuid.UUID, beforeID int64, limit int,
+) ([]WebhookDelivery, error) {
+	logger := logging.FromContext(ctx)
+	query := `SELECT ` + webhookDeliveryColumns + `
+FROM webhook_deliveries
+WHERE subscription_id = $1 AND ($2 = 0 OR id < $2)
+ORDER BY id DESC
+LIMIT $3`
+	list := make([]WebhookDelivery, 0)
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, subscriptionID, beforeID, limit)
+	if err != nil {
+		logger.WithError(err).WithField("webhook_id", subscriptionID).Error("select webhook deliveries failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// ListWebhookDeliveryAttempts returns attempts of the deliveries in the order they were made.
+func ListWebhookDeliveryAttempts(
+	ctx context.Context, dbConn sqlx.QueryerContext, deliveryIDs []int64,
+) ([]WebhookDeliveryAttempt, error) {
+	list := make([]WebhookDeliveryAttempt, 0)
+	if len(deliveryIDs) == 0 {
+		return list, nil
+	}
+	logger := logging.FromContext(ctx)
+	qArgs := new(queryArgs)
+	placeholders := make([]string, 0, len(deliveryIDs))
+	for _, deliveryID := range deliveryIDs {
+		placeholders = append(placeholders, qArgs.add(deliveryID))
+	}
+	query := `SELECT id, delivery_id, attempted_at, status_code, error, duration_ms
+FROM webhook_delivery_attempts
+WHERE delivery_id IN (` + strings.Join(placeholders, ", ") + `)
+ORDER BY id`
+	err := sqlx.SelectContext(ctx, dbConn, &list, query, qArgs.args...)
+	if err != nil {
+		logger.WithError(err).Error("select webhook delivery attempts failed")
+
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+const (
+	LogPublisherName  = "log"
+	FilePublisherName = "file"
+)
+
+const eventsFileMode os.FileMode = 0o600
+
+var ErrUnknownPublisher = errors.New("unknown publisher")
+
+// Event is the company lifecycle event as it is published.
+// Events are delivered at least once, consumers should skip already seen IDs.
+type Event struct {
+	ID        int64               `json:"id"`
+	Type      db.CompanyEventType `json:"type"`
+	CompanyID uuid.UUID           `json:"company_id"`
+	// Payload is the company after the change.
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+	// TenantID is the tenant of the company.
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+func newEvent(dbEvent *db.CompanyEvent) *Event {
+	return &Event{
+		ID:        dbEvent.ID,
+		Type:      dbEvent.Type,
+		CompanyID: dbEvent.CompanyID,
+		Payload:   dbEvent.Payload,
+		CreatedAt: dbEvent.CreatedAt,
+		TenantID:  dbEvent.TenantID,
+	}
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// NewPublisher makes the publisher chosen by the config, the log publisher if the config is missing.
+func NewPublisher(outboxConf *config.Outbox) (Publisher, error) {
+	if outboxConf == nil {
+		return new(LogPublisher), nil
+	}
+	switch outboxConf.Publisher {
+	case LogPublisherName, "":
+		return new(LogPublisher), nil
+	case FilePublisherName:
+		return NewFilePublisher(outboxConf.FilePath), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPublisher, outboxConf.Publisher)
+	}
+}
+
+// MultiPublisher publishes the event by every publisher, it fails if any of them fails.
+// Failed event is published again by all publishers, so they must tolerate duplicates.
+type MultiPublisher []Publisher
+
+func (mp MultiPublisher) Publish(ctx context.Context, event *Event) error {
+	for _, publisher := range mp {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LogPublisher writes events to the log, it is meant for local use.
+type LogPublisher struct{}
+
+func (lp *LogPublisher) Publish(ctx context.Context, event *Event) error {
+	logger := logging.FromContext(ctx)
+	logger.
+		WithFields(logging.Fields{
+			"event_id":   event.ID,
+			"event_type": event.Type,
+			"company_id": event.CompanyID,
+			"payload":    string(event.Payload),
+		}).
+		Info("company event published")
+
+	return nil
+}
+
+// FilePublisher appends events to the file as JSON lines, it is meant for local use.
+type FilePublisher struct {
+	mu       sync.Mutex
+	FilePath string
+}
+
+func NewFilePublisher(filePath string) *FilePublisher {
+	return &FilePublisher{FilePath: filePath}
+}
+
+func (fp *FilePublisher) Publish(ctx context.Context, event *Event) error {
+	logger := logging.FromContext(ctx)
+	encodedEvent, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event failed: %w", err)
+	}
+
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	file, err := os.OpenFile(fp.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, eventsFileMode)
+	if err != nil {
+		return fmt.Errorf("open events file failed: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			logger.WithError(closeErr).Error("close events file failed")
+		}
+	}()
+	if _, err = file.Write(append(encodedEvent, '\n')); err != nil {
+		return fmt.Errorf("write event failed: %w", err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Errorf("sync events file failed: %w", err)
+	}
+
+	return nil
+}
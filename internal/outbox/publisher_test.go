@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+func TestFilePublisher_Publish(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	filePath := filepath.Join(t.TempDir(), "events.jsonl")
+	publisher := NewFilePublisher(filePath)
+
+	createdAt := time.Date(2022, 9, 15, 15, 4, 17, 0, time.UTC)
+	companyID := uuid.MustParse("3997db3d-f747-4f00-adf8-1d2c71d2a911")
+	events := []*Event{
+		{ID: 1, Type: db.CompanyCreated, CompanyID: companyID, Payload: json.RawMessage(`{"name":"ltd"}`), CreatedAt: createdAt},
+		{ID: 2, Type: db.CompanyDeleted, CompanyID: companyID, Payload: json.RawMessage(`{"name":"ltd"}`), CreatedAt: createdAt},
+	}
+	for _, event := range events {
+		if err := publisher.Publish(ctx, event); err != nil {
+			t.Fatalf("publish event failed: %s", err)
+		}
+	}
+
+	gotContent, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("read events file failed: %s", err)
+	}
+	expectedContent := `{"id":1,"type":"CompanyCreated","company_id":"3997db3d-f747-4f00-adf8-1d2c71d2a911",` +
+		`"payload":{"name":"ltd"},"created_at":"2022-09-15T15:04:17Z"}
+{"id":2,"type":"CompanyDeleted","company_id":"3997db3d-f747-4f00-adf8-1d2c71d2a911",` +
+		`"payload":{"name":"ltd"},"created_at":"2022-09-15T15:04:17Z"}
+`
+	assert.Equal(t, expectedContent, string(gotContent), "events file must match")
+}
+
+func TestNewPublisher_Unknown(t *testing.T) {
+	_, err := NewPublisher(&config.Outbox{Publisher: "kafka"})
+	assert.ErrorIs(t, err, ErrUnknownPublisher, "error must match")
+}
+
+func TestNewPublisher_NoConfig(t *testing.T) {
+	publisher, err := NewPublisher(nil)
+	assert.NoError(t, err, "error must be nil")
+	assert.IsType(t, new(LogPublisher), publisher, "publisher must match")
+}
+
+func TestNewRelay_NoConfig(t *testing.T) {
+	relay := NewRelay(nil, new(LogPublisher), nil)
+	assert.Equal(t, DefaultBatchSize, relay.BatchSize, "batch size must match")
+	assert.Equal(t, DefaultPollInterval, relay.PollInterval, "poll interval must match")
+}
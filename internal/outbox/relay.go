@@ -0,0 +1,101 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+)
+
+// Relay publishes pending events of the outbox in the order they were written.
+// Event is marked delivered only after it is published, so it is published at least once.
+type Relay struct {
+	DbConn       *sqlx.DB
+	Publisher    Publisher
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// NewRelay makes the relay by the config, defaults are used for missing settings or missing config.
+func NewRelay(dbConn *sqlx.DB, publisher Publisher, outboxConf *config.Outbox) *Relay {
+	if outboxConf == nil {
+		outboxConf = new(config.Outbox)
+	}
+	relay := &Relay{
+		DbConn:       dbConn,
+		Publisher:    publisher,
+		BatchSize:    outboxConf.BatchSize,
+		PollInterval: outboxConf.PollInterval,
+	}
+	if relay.BatchSize <= 0 {
+		relay.BatchSize = DefaultBatchSize
+	}
+	if relay.PollInterval <= 0 {
+		relay.PollInterval = DefaultPollInterval
+	}
+
+	return relay
+}
+
+// Run relays events until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+	for {
+		relayed, err := r.RelayBatch(ctx)
+		if err != nil {
+			logger.WithError(err).Error("relay company events failed")
+		}
+		// full batch means there can be more pending events
+		if err == nil && relayed == r.BatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayBatch publishes one batch of pending events and returns the number of delivered ones.
+// Publishing stops at the first failed event, so events of the company are never reordered.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	logger := logging.FromContext(ctx)
+	delivered := make([]int64, 0, r.BatchSize)
+	var publishErr error
+	err := db.WithTx(ctx, r.DbConn, func(tx *sqlx.Tx) error {
+		events, err := db.LockPendingCompanyEvents(ctx, tx, r.BatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range events {
+			if publishErr = r.Publisher.Publish(ctx, newEvent(&events[i])); publishErr != nil {
+				logger.WithError(publishErr).WithField("event_id", events[i].ID).Error("publish company event failed")
+				if err = db.MarkCompanyEventFailed(ctx, tx, events[i].ID, publishErr); err != nil {
+					return err
+				}
+
+				break
+			}
+			delivered = append(delivered, events[i].ID)
+		}
+
+		return db.MarkCompanyEventsDelivered(ctx, tx, delivered, time.Now().UTC())
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(delivered), publishErr
+}
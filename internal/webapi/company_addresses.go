@@ -0,0 +1,223 @@
+package webapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// Limits follow the columns of company_addresses table.
+const (
+	MaxStreetLength   = 255
+	MaxCityLength     = 128
+	MaxPostcodeLength = 16
+	MaxRegionLength   = 128
+)
+
+type InputAddress struct {
+	Type     db.AddressType `json:"type"`
+	Street   string         `json:"street"`
+	City     string         `json:"city"`
+	Postcode string         `json:"postcode"`
+	Region   string         `json:"region"`
+	// Country is ISO 3166-1 alpha-2 code.
+	Country string `json:"country"`
+}
+
+type AddressResponse struct {
+	ID uuid.UUID `json:"id"`
+	InputAddress
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newAddressResponse(address *db.CompanyAddress) AddressResponse {
+	return AddressResponse{
+		ID: address.ID,
+		InputAddress: InputAddress{
+			Type:     address.Type,
+			Street:   address.Street,
+			City:     address.City,
+			Postcode: address.Postcode,
+			Region:   address.Region,
+			Country:  address.Country,
+		},
+		CreatedAt: address.CreatedAt,
+		UpdatedAt: address.UpdatedAt,
+	}
+}
+
+func newAddressesResponse(addresses []db.CompanyAddress) []AddressResponse {
+	response := make([]AddressResponse, 0, len(addresses))
+	for i := range addresses {
+		response = append(response, newAddressResponse(&addresses[i]))
+	}
+
+	return response
+}
+
+// postcodeFormat is the postcode format of the country, example is shown in the validation error.
+type postcodeFormat struct {
+	pattern *regexp.Regexp
+	example string
+}
+
+// postcodeFormats are formats of the countries we serve, postcodes of other countries are only limited by length.
+var postcodeFormats = map[string]postcodeFormat{
+	"AT": {pattern: regexp.MustCompile(`^\d{4}$`), example: "1010"},
+	"AU": {pattern: regexp.MustCompile(`^\d{4}$`), example: "2000"},
+	"BE": {pattern: regexp.MustCompile(`^\d{4}$`), example: "1000"},
+	"CA": {pattern: regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`), example: "K1A 0B1"},
+	"CH": {pattern: regexp.MustCompile(`^\d{4}$`), example: "8001"},
+	"CY": {pattern: regexp.MustCompile(`^\d{4}$`), example: "1010"},
+	"DE": {pattern: regexp.MustCompile(`^\d{5}$`), example: "10115"},
+	"ES": {pattern: regexp.MustCompile(`^\d{5}$`), example: "28001"},
+	"FR": {pattern: regexp.MustCompile(`^\d{5}$`), example: "75001"},
+	"GB": {
+		pattern: regexp.MustCompile(`^(GIR 0AA|[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2})$`),
+		example: "SW1A 1AA",
+	},
+	"GR": {pattern: regexp.MustCompile(`^\d{3} ?\d{2}$`), example: "105 57"},
+	"IT": {pattern: regexp.MustCompile(`^\d{5}$`), example: "00118"},
+	"JP": {pattern: regexp.MustCompile(`^\d{3}-?\d{4}$`), example: "100-0001"},
+	"NL": {pattern: regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`), example: "1012 AB"},
+	"PL": {pattern: regexp.MustCompile(`^\d{2}-\d{3}$`), example: "00-001"},
+	"RU": {pattern: regexp.MustCompile(`^\d{6}$`), example: "101000"},
+	"US": {pattern: regexp.MustCompile(`^\d{5}(-\d{4})?$`), example: "10001"},
+}
+
+// Validate returns the list of invalid fields, empty list means the address is valid.
+func (ia *InputAddress) Validate() FieldErrors {
+	errs := make(FieldErrors, 0)
+
+	switch {
+	case ia.Type == "":
+		errs.add("type", "is required")
+	case !isAddressType(ia.Type):
+		errs.add("type", "must be one of "+addressTypesList())
+	}
+
+	switch {
+	case ia.Street == "":
+		errs.add("street", "is required")
+	case utf8.RuneCountInString(ia.Street) > MaxStreetLength:
+		errs.add("street", fmt.Sprintf("must be at most %d characters", MaxStreetLength))
+	}
+
+	switch {
+	case ia.City == "":
+		errs.add("city", "is required")
+	case utf8.RuneCountInString(ia.City) > MaxCityLength:
+		errs.add("city", fmt.Sprintf("must be at most %d characters", MaxCityLength))
+	}
+
+	if utf8.RuneCountInString(ia.Region) > MaxRegionLength {
+		errs.add("region", fmt.Sprintf("must be at most %d characters", MaxRegionLength))
+	}
+
+	countryValid := false
+	switch {
+	case ia.Country == "":
+		errs.add("country", "is required")
+	case !isCountryCode(ia.Country):
+		errs.add("country", "must be ISO 3166-1 alpha-2 code")
+	default:
+		countryValid = true
+	}
+
+	format, hasFormat := postcodeFormats[strings.ToUpper(ia.Country)]
+	switch {
+	case ia.Postcode == "" && hasFormat:
+		errs.add("postcode", "is required")
+	case ia.Postcode == "":
+	case utf8.RuneCountInString(ia.Postcode) > MaxPostcodeLength:
+		errs.add("postcode", fmt.Sprintf("must be at most %d characters", MaxPostcodeLength))
+	case countryValid && hasFormat && !format.pattern.MatchString(strings.ToUpper(ia.Postcode)):
+		errs.add("postcode", fmt.Sprintf("must be postcode of %s like %s", strings.ToUpper(ia.Country), format.example))
+	}
+
+	return errs
+}
+
+// dbAddress fills the address with the input, the country and the postcode are stored in upper case.
+func (ia *InputAddress) dbAddress(address *db.CompanyAddress) {
+	address.Type = ia.Type
+	address.Street = ia.Street
+	address.City = ia.City
+	address.Postcode = strings.ToUpper(ia.Postcode)
+	address.Region = ia.Region
+	address.Country = strings.ToUpper(ia.Country)
+}
+
+func isAddressType(addressType db.AddressType) bool {
+	for _, knownType := range db.AddressTypes {
+		if addressType == knownType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func addressTypesList() string {
+	types := make([]string, 0, len(db.AddressTypes))
+	for _, addressType := range db.AddressTypes {
+		types = append(types, string(addressType))
+	}
+
+	return strings.Join(types, ", ")
+}
+
+// parseAddressURLParams parses companyID and addressID of the address URL.
+// If they are invalid, the response is written and false is returned.
+func parseAddressURLParams(ctx context.Context, w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	logger := logging.FromContext(ctx)
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return uuid.Nil, uuid.Nil, false
+	}
+	urlAddressID := chi.URLParam(r, "addressID")
+	addressID, err := uuid.Parse(urlAddressID)
+	if err != nil {
+		logger.WithError(err).WithField("address_id", urlAddressID).Warn("parse addressID failed")
+		BadRequest(ctx, w, "invalid addressID")
+
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return companyID, addressID, true
+}
+
+// addressWriteFailed responds to the failed change of the address.
+func addressWriteFailed(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrRegisteredAddressExists):
+		Conflict(ctx, w, "company already has registered address", nil)
+	case errors.Is(err, db.ErrCompanyAddressNotFound):
+		NotFound(ctx, w, "address not found")
+	case errors.Is(err, sql.ErrNoRows):
+		NotFound(ctx, w, "company not found")
+	default:
+		InternalServerError(ctx, w, "save address failed")
+	}
+}
+
+func NewAddressID() uuid.UUID {
+	return uuid.New()
+}
@@ -0,0 +1,245 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type CompanyAddressesSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestCompanyAddressesSuite(t *testing.T) {
+	s := new(CompanyAddressesSuite)
+	suite.Run(t, s)
+}
+
+func (s *CompanyAddressesSuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil).
+		Maybe()
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("addresses-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *CompanyAddressesSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *CompanyAddressesSuite) TestCompanyAddresses_OK() {
+	t := s.T()
+
+	// create the company
+	companyID := "c3d4e5f6-a7b8-4c9d-8e0f-1a2b3c4d5e6f"
+	addressIDs := []string{"d4e5f6a7-b8c9-4d0e-9f1a-2b3c4d5e6f7a", "e5f6a7b8-c9d0-4e1f-8a2b-3c4d5e6f7a8b"}
+	fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+		return uuid.MustParse(companyID)
+	})
+	defer fakePatch.Reset()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "ADDRESSES", "country": "CY", "type": "Corporation"}`), metadata)
+	assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+
+	// add registered and operational addresses
+	bodies := []string{
+		`{"type": "registered", "street": "1 Main St", "city": "Limassol", "postcode": "3030", "country": "cy"}`,
+		`{"type": "operational", "street": "2 High St", "city": "London", "postcode": "sw1a 1aa", "country": "GB"}`,
+	}
+	for i, addressID := range addressIDs {
+		addressPatch := gomonkey.ApplyFunc(NewAddressID, func() uuid.UUID {
+			return uuid.MustParse(addressID)
+		})
+		response = makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/"+companyID+"/addresses",
+			strings.NewReader(bodies[i]), metadata)
+		addressPatch.Reset()
+		assert.Equal(t, http.StatusCreated, response.Code, "http code of add address must match")
+	}
+
+	// the second registered address conflicts
+	response = makeTestRequest(s.router, http.MethodPut,
+		"/api/v1/companies/"+companyID+"/addresses/"+addressIDs[1],
+		strings.NewReader(`{"type": "registered", "street": "2 High St", "city": "London", `+
+			`"postcode": "SW1A 1AA", "country": "GB"}`),
+		metadata)
+	assert.Equal(t, http.StatusConflict, response.Code, "http code of conflicting update must match")
+	assert.JSONEq(t, `{"error": "company already has registered address"}`, response.Body.String(),
+		"http body of conflicting update must match")
+
+	// update the operational address
+	response = makeTestRequest(s.router, http.MethodPut,
+		"/api/v1/companies/"+companyID+"/addresses/"+addressIDs[1],
+		strings.NewReader(`{"type": "billing", "street": "2 High St", "city": "London", `+
+			`"postcode": "SW1A 1AA", "country": "GB"}`),
+		metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of update address must match")
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyID+"?include=addresses",
+		nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data GetCompanyResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	assert.Nil(t, gotBody.Data.Contacts, "contacts must not be included")
+	if !assert.NotNil(t, gotBody.Data.Addresses, "addresses must be included") {
+		return
+	}
+	gotAddresses := make([]InputAddress, 0)
+	for _, address := range *gotBody.Data.Addresses {
+		gotAddresses = append(gotAddresses, address.InputAddress)
+	}
+	expectedAddresses := []InputAddress{
+		{Type: db.AddressTypeRegistered, Street: "1 Main St", City: "Limassol", Postcode: "3030", Country: "CY"},
+		{Type: db.AddressTypeBilling, Street: "2 High St", City: "London", Postcode: "SW1A 1AA", Country: "GB"},
+	}
+	assert.Equal(t, expectedAddresses, gotAddresses, "addresses must match")
+
+	// filter companies by the address
+	response = makeTestRequest(s.router, http.MethodGet,
+		"/api/v1/companies?address_city=london&address_country=gb", nil, metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of list must match")
+	assert.Contains(t, response.Body.String(), companyID, "company must be found by the address")
+	response = makeTestRequest(s.router, http.MethodGet,
+		"/api/v1/companies?address_city=london&address_country=cy", nil, metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of list must match")
+	assert.NotContains(t, response.Body.String(), companyID, "city and country must match the same address")
+
+	// delete the address
+	response = makeTestRequest(s.router, http.MethodDelete,
+		"/api/v1/companies/"+companyID+"/addresses/"+addressIDs[1], nil, metadata)
+	assert.Equal(t, http.StatusNoContent, response.Code, "http code of delete address must match")
+	response = makeTestRequest(s.router, http.MethodDelete,
+		"/api/v1/companies/"+companyID+"/addresses/"+addressIDs[1], nil, metadata)
+	assert.Equal(t, http.StatusNotFound, response.Code, "http code of delete deleted address must match")
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyID+"/addresses", nil, metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of list addresses must match")
+	listBody := new(struct {
+		Data CompanyAddressesResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(listBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if assert.Len(t, listBody.Data, 1, "addresses count must match") {
+		assert.Equal(t, addressIDs[0], listBody.Data[0].ID.String(), "address id must match")
+	}
+}
+
+func (s *CompanyAddressesSuite) TestCompanyAddresses_Invalid() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/"+uuid.New().String()+"/addresses",
+		strings.NewReader(`{"type": "home", "street": "1 Main St", "city": "Berlin", "postcode": "1011", "country": "DE"}`),
+		metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code must match")
+
+	// assert HTTP body
+	expectedBody := `{
+	"error": "invalid address",
+	"details": [
+		{"field": "type", "message": "must be one of registered, operational, billing"},
+		{"field": "postcode", "message": "must be postcode of DE like 10115"}
+	]
+}`
+	assert.JSONEq(t, expectedBody, response.Body.String(), "http body must match")
+}
+
+func TestInputAddress_Validate(t *testing.T) {
+	testCases := []struct {
+		input          InputAddress
+		expectedErrors FieldErrors
+	}{
+		{
+			input: InputAddress{
+				Type: db.AddressTypeBilling, Street: "1", City: "Nicosia", Postcode: "1010", Country: "CY",
+			},
+			expectedErrors: FieldErrors{},
+		},
+		{
+			input:          InputAddress{Type: db.AddressTypeBilling, Street: "1", City: "Kabul", Country: "AF"},
+			expectedErrors: FieldErrors{},
+		},
+		{
+			input: InputAddress{Type: db.AddressTypeRegistered, Street: "1", City: "Boston", Postcode: "0211", Country: "us"},
+			expectedErrors: FieldErrors{
+				{Field: "postcode", Message: "must be postcode of US like 10001"},
+			},
+		},
+		{
+			input: InputAddress{Type: db.AddressTypeOperational, Street: "1", City: "Amsterdam", Country: "NL"},
+			expectedErrors: FieldErrors{
+				{Field: "postcode", Message: "is required"},
+			},
+		},
+		{
+			input: InputAddress{Postcode: strings.Repeat("1", MaxPostcodeLength+1), Country: "XX"},
+			expectedErrors: FieldErrors{
+				{Field: "type", Message: "is required"},
+				{Field: "street", Message: "is required"},
+				{Field: "city", Message: "is required"},
+				{Field: "country", Message: "must be ISO 3166-1 alpha-2 code"},
+				{Field: "postcode", Message: "must be at most 16 characters"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedErrors, tc.input.Validate(), "errors of %+v must match", tc.input)
+	}
+}
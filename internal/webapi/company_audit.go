@@ -0,0 +1,87 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// companyChange is the state of the company before and after the change, Before is nil on create.
+type companyChange struct {
+	Before *db.Company
+	After  *db.Company
+}
+
+// companyEventTypes are types of outbox events published on company changes.
+var companyEventTypes = map[db.CompanyAuditAction]db.CompanyEventType{
+	db.CompanyAuditCreate:  db.CompanyCreated,
+	db.CompanyAuditUpdate:  db.CompanyUpdated,
+	db.CompanyAuditDelete:  db.CompanyDeleted,
+	db.CompanyAuditRestore: db.CompanyRestored,
+}
+
+// recordCompanyChanges appends the changes made by the client of the request to the company history
+// and puts events of the changes into the outbox, dbConn must be the transaction of the changes.
+func recordCompanyChanges(
+	ctx context.Context, dbConn db.ExecNamedExerContext, action db.CompanyAuditAction, changes ...companyChange,
+) error {
+	actor := authn.Actor(ctx)
+	var requestID *uuid.UUID
+	if id := RequestIDFromContext(ctx); id != uuid.Nil {
+		requestID = &id
+	}
+	createdAt := time.Now().UTC()
+
+	records := make([]db.CompanyAuditRecord, 0, len(changes))
+	events := make([]db.CompanyEvent, 0, len(changes))
+	for _, change := range changes {
+		record := db.CompanyAuditRecord{
+			CompanyID: change.After.ID,
+			Action:    action,
+			Actor:     actor,
+			RequestID: requestID,
+			CreatedAt: createdAt,
+		}
+		var err error
+		if change.Before != nil {
+			record.Before, err = json.Marshal(newCompanyResponse(change.Before))
+			if err != nil {
+				return fmt.Errorf("encode company before change failed: %w", err)
+			}
+		}
+		record.After, err = json.Marshal(newCompanyResponse(change.After))
+		if err != nil {
+			return fmt.Errorf("encode company after change failed: %w", err)
+		}
+		records = append(records, record)
+		events = append(events, db.CompanyEvent{
+			Type:      companyEventTypes[action],
+			CompanyID: record.CompanyID,
+			Payload:   record.After,
+			CreatedAt: createdAt,
+		})
+	}
+
+	if err := db.InsertCompanyAudit(ctx, dbConn, records); err != nil {
+		return err
+	}
+
+	return db.InsertCompanyEvents(ctx, dbConn, events)
+}
+
+// deletedCompany returns the company as it is after soft delete at deletedAt by the actor deletedBy.
+func deletedCompany(dbCompany *db.Company, deletedAt time.Time, deletedBy string) *db.Company {
+	deleted := *dbCompany
+	deleted.DeletedAt = &deletedAt
+	deleted.UpdatedAt = deletedAt
+	deleted.UpdatedBy = deletedBy
+	deleted.Version++
+
+	return &deleted
+}
@@ -0,0 +1,180 @@
+package webapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// Limits follow the columns of company_contacts table.
+const (
+	MaxContactLabelLength = 64
+	MaxContactNameLength  = 255
+	MaxEmailLength        = 254 // RFC 5321 limit of the path
+)
+
+type InputContact struct {
+	Type  db.ContactType `json:"type"`
+	Value string         `json:"value"`
+	Label string         `json:"label"`
+	// Name is the contact person.
+	Name    string `json:"name"`
+	Primary bool   `json:"primary"`
+}
+
+type ContactResponse struct {
+	ID uuid.UUID `json:"id"`
+	InputContact
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newContactResponse(contact *db.CompanyContact) ContactResponse {
+	return ContactResponse{
+		ID: contact.ID,
+		InputContact: InputContact{
+			Type:    contact.Type,
+			Value:   contact.Value,
+			Label:   contact.Label,
+			Name:    contact.Name,
+			Primary: contact.Primary,
+		},
+		CreatedAt: contact.CreatedAt,
+		UpdatedAt: contact.UpdatedAt,
+	}
+}
+
+func newContactsResponse(contacts []db.CompanyContact) []ContactResponse {
+	response := make([]ContactResponse, 0, len(contacts))
+	for i := range contacts {
+		response = append(response, newContactResponse(&contacts[i]))
+	}
+
+	return response
+}
+
+// Validate returns the list of invalid fields, empty list means the contact is valid.
+func (ic *InputContact) Validate() FieldErrors {
+	errs := make(FieldErrors, 0)
+
+	switch ic.Type {
+	case db.ContactTypePhone, db.ContactTypeFax:
+		switch {
+		case ic.Value == "":
+			errs.add("value", "is required")
+		case utf8.RuneCountInString(ic.Value) > MaxPhoneLength:
+			errs.add("value", fmt.Sprintf("must be at most %d characters", MaxPhoneLength))
+		case !isPhone(ic.Value):
+			errs.add("value", fmt.Sprintf("must be phone number of %d-%d digits", MinPhoneDigits, MaxPhoneDigits))
+		}
+	case db.ContactTypeEmail:
+		switch {
+		case ic.Value == "":
+			errs.add("value", "is required")
+		case utf8.RuneCountInString(ic.Value) > MaxEmailLength:
+			errs.add("value", fmt.Sprintf("must be at most %d characters", MaxEmailLength))
+		case !isEmail(ic.Value):
+			errs.add("value", "must be email address")
+		}
+	case "":
+		errs.add("type", "is required")
+	default:
+		errs.add("type", "must be one of "+contactTypesList())
+	}
+
+	if utf8.RuneCountInString(ic.Label) > MaxContactLabelLength {
+		errs.add("label", fmt.Sprintf("must be at most %d characters", MaxContactLabelLength))
+	}
+	if utf8.RuneCountInString(ic.Name) > MaxContactNameLength {
+		errs.add("name", fmt.Sprintf("must be at most %d characters", MaxContactNameLength))
+	}
+
+	return errs
+}
+
+// isEmail accepts the bare address without the display name.
+func isEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+
+	return err == nil && address.Address == email
+}
+
+func contactTypesList() string {
+	types := make([]string, 0, len(db.ContactTypes))
+	for _, contactType := range db.ContactTypes {
+		types = append(types, string(contactType))
+	}
+
+	return strings.Join(types, ", ")
+}
+
+// parseContactURLParams parses companyID and contactID of the contact URL.
+// If they are invalid, the response is written and false is returned.
+func parseContactURLParams(ctx context.Context, w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	logger := logging.FromContext(ctx)
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return uuid.Nil, uuid.Nil, false
+	}
+	urlContactID := chi.URLParam(r, "contactID")
+	contactID, err := uuid.Parse(urlContactID)
+	if err != nil {
+		logger.WithError(err).WithField("contact_id", urlContactID).Warn("parse contactID failed")
+		BadRequest(ctx, w, "invalid contactID")
+
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return companyID, contactID, true
+}
+
+// withCompanyLocked runs fn in the transaction locking the not deleted company,
+// so contacts of the company are changed one by one.
+func withCompanyLocked(
+	ctx context.Context, dbConn *sqlx.DB, companyID uuid.UUID, fn func(tx *sqlx.Tx) error,
+) error {
+	return db.WithTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+		dbCompany, err := db.GetCompanyByIDForUpdate(ctx, tx, companyID)
+		if err != nil {
+			return err
+		}
+		if dbCompany.DeletedAt != nil {
+			return db.ErrCompanyNotFound
+		}
+
+		return fn(tx)
+	})
+}
+
+// contactWriteFailed responds to the failed change of the contact.
+func contactWriteFailed(ctx context.Context, w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrCompanyContactNotFound):
+		NotFound(ctx, w, "contact not found")
+	case errors.Is(err, sql.ErrNoRows):
+		NotFound(ctx, w, "company not found")
+	default:
+		InternalServerError(ctx, w, "save contact failed")
+	}
+}
+
+func NewContactID() uuid.UUID {
+	return uuid.New()
+}
@@ -0,0 +1,194 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type CompanyContactsSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestCompanyContactsSuite(t *testing.T) {
+	s := new(CompanyContactsSuite)
+	suite.Run(t, s)
+}
+
+func (s *CompanyContactsSuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil).
+		Maybe()
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("contacts-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *CompanyContactsSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *CompanyContactsSuite) TestCompanyContacts_OK() {
+	t := s.T()
+
+	// create the company
+	companyID := "8a9b0c1d-2e3f-4a5b-8c6d-7e8f9a0b1c2d"
+	contactIDs := []string{"9b0c1d2e-3f4a-4b5c-9d6e-7f8a9b0c1d2e", "ac1d2e3f-4a5b-4c6d-8e7f-8a9b0c1d2e3f"}
+	fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+		return uuid.MustParse(companyID)
+	})
+	defer fakePatch.Reset()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "CONTACTS", "country": "CY", "type": "Corporation"}`), metadata)
+	assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+
+	// add two primary phones, the second one replaces the first one
+	for i, contactID := range contactIDs {
+		contactPatch := gomonkey.ApplyFunc(NewContactID, func() uuid.UUID {
+			return uuid.MustParse(contactID)
+		})
+		body := fmt.Sprintf(`{"type": "phone", "value": "+35722%06d", "label": "office", "primary": true}`, i)
+		response = makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/"+companyID+"/contacts",
+			strings.NewReader(body), metadata)
+		contactPatch.Reset()
+		assert.Equal(t, http.StatusCreated, response.Code, "http code of add contact must match")
+	}
+
+	// update the first contact
+	response = makeTestRequest(s.router, http.MethodPut, "/api/v1/companies/"+companyID+"/contacts/"+contactIDs[0],
+		strings.NewReader(`{"type": "email", "value": "sales@example.com", "name": "John Doe", "primary": true}`),
+		metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of update contact must match")
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyID+"?include=contacts", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data GetCompanyResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if !assert.NotNil(t, gotBody.Data.Contacts, "contacts must be included") {
+		return
+	}
+	gotContacts := make([]InputContact, 0)
+	for _, contact := range *gotBody.Data.Contacts {
+		gotContacts = append(gotContacts, contact.InputContact)
+	}
+	expectedContacts := []InputContact{
+		{Type: db.ContactTypeEmail, Value: "sales@example.com", Name: "John Doe", Primary: true},
+		{Type: db.ContactTypePhone, Value: "+35722000001", Label: "office", Primary: true},
+	}
+	assert.Equal(t, expectedContacts, gotContacts, "contacts must match")
+
+	// delete the contact
+	response = makeTestRequest(s.router, http.MethodDelete,
+		"/api/v1/companies/"+companyID+"/contacts/"+contactIDs[1], nil, metadata)
+	assert.Equal(t, http.StatusNoContent, response.Code, "http code of delete contact must match")
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyID+"/contacts", nil, metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of list contacts must match")
+	listBody := new(struct {
+		Data CompanyContactsResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(listBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if assert.Len(t, listBody.Data, 1, "contacts count must match") {
+		assert.Equal(t, contactIDs[0], listBody.Data[0].ID.String(), "contact id must match")
+	}
+}
+
+func (s *CompanyContactsSuite) TestCompanyContacts_NotFound() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/"+uuid.New().String()+"/contacts",
+		strings.NewReader(`{"type": "fax", "value": "+35722000000"}`), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusNotFound, response.Code, "http code must match")
+
+	// assert HTTP body
+	assert.JSONEq(t, `{"error": "company not found"}`, response.Body.String(), "http body must match")
+}
+
+func TestInputContact_Validate(t *testing.T) {
+	testCases := []struct {
+		input          InputContact
+		expectedErrors FieldErrors
+	}{
+		{
+			input:          InputContact{Type: db.ContactTypeFax, Value: "+357 22 000000"},
+			expectedErrors: FieldErrors{},
+		},
+		{
+			input:          InputContact{Type: db.ContactTypeEmail, Value: "John <john@example.com>"},
+			expectedErrors: FieldErrors{{Field: "value", Message: "must be email address"}},
+		},
+		{
+			input:          InputContact{Type: db.ContactTypePhone, Value: "call me"},
+			expectedErrors: FieldErrors{{Field: "value", Message: "must be phone number of 4-15 digits"}},
+		},
+		{
+			input: InputContact{Type: "telex", Label: strings.Repeat("x", MaxContactLabelLength+1)},
+			expectedErrors: FieldErrors{
+				{Field: "type", Message: "must be one of phone, email, fax"},
+				{Field: "label", Message: "must be at most 64 characters"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedErrors, tc.input.Validate(), "errors of %+v must match", tc.input)
+	}
+}
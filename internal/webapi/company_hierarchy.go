@@ -0,0 +1,77 @@
+package webapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// CompanyTreeNode is the company with its subsidiaries.
+type CompanyTreeNode struct {
+	CompanyResponse
+	Children []*CompanyTreeNode `json:"children"`
+}
+
+// newCompanyTree builds the tree of companies returned by db.ListCompanyDescendants, the first one is the root.
+func newCompanyTree(dbCompanies []db.Company) *CompanyTreeNode {
+	if len(dbCompanies) == 0 {
+		return nil
+	}
+	nodes := make(map[uuid.UUID]*CompanyTreeNode, len(dbCompanies))
+	var root *CompanyTreeNode
+	for i := range dbCompanies {
+		node := &CompanyTreeNode{
+			CompanyResponse: newCompanyResponse(&dbCompanies[i]),
+			Children:        make([]*CompanyTreeNode, 0),
+		}
+		nodes[node.ID] = node
+		if i == 0 {
+			root = node
+
+			continue
+		}
+		// parents go before children, so the parent node already exists
+		if parent, ok := nodes[*dbCompanies[i].ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return root
+}
+
+// getHierarchyCompany returns the not deleted company of the URL.
+// If it is not found, the response is written and false is returned.
+func getHierarchyCompany(
+	ctx context.Context, w http.ResponseWriter, r *http.Request, dbConn db.RowxQueryerContext,
+) (*db.Company, bool) {
+	logger := logging.FromContext(ctx)
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return nil, false
+	}
+	dbCompany, err := db.GetCompanyByID(ctx, dbConn, companyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Warn("get company failed")
+		if errors.Is(err, sql.ErrNoRows) {
+			NotFound(ctx, w, "company not found")
+
+			return nil, false
+		}
+		InternalServerError(ctx, w, "get company failed")
+
+		return nil, false
+	}
+
+	return dbCompany, true
+}
@@ -0,0 +1,234 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type CompanyHierarchySuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestCompanyHierarchySuite(t *testing.T) {
+	s := new(CompanyHierarchySuite)
+	suite.Run(t, s)
+}
+
+func (s *CompanyHierarchySuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil).
+		Maybe()
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("hierarchy-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *CompanyHierarchySuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *CompanyHierarchySuite) TestCompanyHierarchy_OK() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// create the group: holding <- subsidiary <- branch
+	holdingID := "0a1b2c3d-4e5f-4a6b-8c7d-8e9f0a1b2c3d"
+	subsidiaryID := "1b2c3d4e-5f6a-4b7c-9d8e-9f0a1b2c3d4e"
+	branchID := "2c3d4e5f-6a7b-4c8d-8e9f-0a1b2c3d4e5f"
+	for i, companyID := range []string{holdingID, subsidiaryID, branchID} {
+		fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+			return uuid.MustParse(companyID)
+		})
+		body := fmt.Sprintf(`{"name": "ltd %d", "code": "GROUP-%d", "country": "CY", "type": "Corporation"}`, i, i)
+		response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies", strings.NewReader(body), metadata)
+		fakePatch.Reset()
+		assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+	}
+	parents := []struct {
+		companyID string
+		parentID  string
+	}{
+		{companyID: subsidiaryID, parentID: holdingID},
+		{companyID: branchID, parentID: subsidiaryID},
+	}
+	for _, parent := range parents {
+		response := makeTestRequest(s.router, http.MethodPut, "/api/v1/companies/"+parent.companyID+"/parent",
+			strings.NewReader(fmt.Sprintf(`{"parent_id": %q}`, parent.parentID)), metadata)
+		assert.Equal(t, http.StatusOK, response.Code, "http code of set parent must match")
+		assert.Equal(t, `"2"`, response.Header().Get("ETag"), "etag of set parent must match")
+	}
+
+	// cycle is rejected
+	response := makeTestRequest(s.router, http.MethodPut, "/api/v1/companies/"+holdingID+"/parent",
+		strings.NewReader(fmt.Sprintf(`{"parent_id": %q}`, branchID)), metadata)
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code of cycle must match")
+	expectedBody := `{
+	"error": "invalid parent",
+	"details": [{"field": "parent_id", "message": "must not be the company or its subsidiary"}]
+}`
+	assert.JSONEq(t, expectedBody, response.Body.String(), "http body of cycle must match")
+
+	// list children and ancestors
+	testCases := []struct {
+		path        string
+		expectedIDs []string
+	}{
+		{path: "/api/v1/companies/" + holdingID + "/children", expectedIDs: []string{subsidiaryID}},
+		{path: "/api/v1/companies/" + branchID + "/children", expectedIDs: []string{}},
+		{path: "/api/v1/companies/" + branchID + "/ancestors", expectedIDs: []string{subsidiaryID, holdingID}},
+		{path: "/api/v1/companies/" + holdingID + "/ancestors", expectedIDs: []string{}},
+	}
+	for _, tc := range testCases {
+		response = makeTestRequest(s.router, http.MethodGet, tc.path, nil, metadata)
+		assert.Equal(t, http.StatusOK, response.Code, "http code of %s must match", tc.path)
+		gotBody := new(struct {
+			Data CompaniesListResponse `json:"data"`
+		})
+		if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+			t.Fatalf("decode response body failed: %s", err)
+		}
+		gotIDs := make([]string, 0)
+		for _, company := range gotBody.Data {
+			gotIDs = append(gotIDs, company.ID.String())
+		}
+		assert.Equal(t, tc.expectedIDs, gotIDs, "companies of %s must match", tc.path)
+	}
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+branchID+"/tree", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotTree := new(struct {
+		Data CompanyTreeNode `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotTree); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	assert.Equal(t, holdingID, gotTree.Data.ID.String(), "root must be the holding")
+	if assert.Len(t, gotTree.Data.Children, 1, "children of the holding must match") {
+		subsidiary := gotTree.Data.Children[0]
+		assert.Equal(t, subsidiaryID, subsidiary.ID.String(), "child of the holding must match")
+		if assert.Len(t, subsidiary.Children, 1, "children of the subsidiary must match") {
+			assert.Equal(t, branchID, subsidiary.Children[0].ID.String(), "child of the subsidiary must match")
+			assert.Equal(t, subsidiaryID, subsidiary.Children[0].ParentID.String(), "parent of the branch must match")
+		}
+	}
+
+	// clear the parent with the stale etag, then with the current one
+	metadata.headers["If-Match"] = `"1"`
+	response = makeTestRequest(s.router, http.MethodPut, "/api/v1/companies/"+subsidiaryID+"/parent",
+		strings.NewReader(`{"parent_id": null}`), metadata)
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code, "http code of stale etag must match")
+	metadata.headers["If-Match"] = `"2"`
+	response = makeTestRequest(s.router, http.MethodPut, "/api/v1/companies/"+subsidiaryID+"/parent",
+		strings.NewReader(`{"parent_id": null}`), metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of clear parent must match")
+	dbCompany := selectDbCompanyByID(t, s.dbConn, subsidiaryID)
+	assert.Equal(t, int64(3), dbCompany.Version, "version must be incremented")
+}
+
+func (s *CompanyHierarchySuite) TestCompanyHierarchy_ParentNotFound() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	companyID := "3d4e5f6a-7b8c-4d9e-9f0a-1b2c3d4e5f6a"
+	fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+		return uuid.MustParse(companyID)
+	})
+	defer fakePatch.Reset()
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "ORPHAN", "country": "CY", "type": "Corporation"}`), metadata)
+	assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodPut, "/api/v1/companies/"+companyID+"/parent",
+		strings.NewReader(fmt.Sprintf(`{"parent_id": %q}`, uuid.New())), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code must match")
+
+	// assert HTTP body
+	expectedBody := `{
+	"error": "invalid parent",
+	"details": [{"field": "parent_id", "message": "company not found"}]
+}`
+	assert.JSONEq(t, expectedBody, response.Body.String(), "http body must match")
+}
+
+func TestNewCompanyTree(t *testing.T) {
+	rootID := uuid.MustParse("4e5f6a7b-8c9d-4e0f-8a1b-2c3d4e5f6a7b")
+	childIDs := []uuid.UUID{
+		uuid.MustParse("5f6a7b8c-9d0e-4f1a-9b2c-3d4e5f6a7b8c"),
+		uuid.MustParse("6a7b8c9d-0e1f-4a2b-8c3d-4e5f6a7b8c9d"),
+	}
+	grandchildID := uuid.MustParse("7b8c9d0e-1f2a-4b3c-9d4e-5f6a7b8c9d0e")
+	dbCompanies := []db.Company{
+		{ID: rootID},
+		{ID: childIDs[0], ParentID: &rootID},
+		{ID: childIDs[1], ParentID: &rootID},
+		{ID: grandchildID, ParentID: &childIDs[1]},
+	}
+
+	tree := newCompanyTree(dbCompanies)
+
+	if !assert.NotNil(t, tree, "tree must be built") {
+		return
+	}
+	assert.Equal(t, rootID, tree.ID, "root must match")
+	if assert.Len(t, tree.Children, 2, "children of the root must match") {
+		assert.Equal(t, childIDs[0], tree.Children[0].ID, "first child must match")
+		assert.Empty(t, tree.Children[0].Children, "first child must have no children")
+		assert.Equal(t, childIDs[1], tree.Children[1].ID, "second child must match")
+		if assert.Len(t, tree.Children[1].Children, 1, "children of the second child must match") {
+			assert.Equal(t, grandchildID, tree.Children[1].Children[0].ID, "grandchild must match")
+		}
+	}
+	assert.Nil(t, newCompanyTree(nil), "empty tree must be nil")
+}
@@ -0,0 +1,147 @@
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+const (
+	// MaxTagLength follows the column of tags table.
+	MaxTagLength = 64
+	// MaxTagsCount limits the number of tags attached by one request and used by the filter.
+	MaxTagsCount = 20
+)
+
+// tagPattern allows names like segment:enterprise or priority-high, names are compared in lower case.
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]*$`)
+
+// normalizeTag returns the name of the tag as it is saved.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// validateTag returns the message if the normalized tag is invalid.
+func validateTag(tag string) string {
+	switch {
+	case tag == "":
+		return "is required"
+	case len(tag) > MaxTagLength:
+		return fmt.Sprintf("must be at most %d characters", MaxTagLength)
+	case !tagPattern.MatchString(tag):
+		return "must contain only latin letters, digits and _.:- starting with a letter or a digit"
+	}
+
+	return ""
+}
+
+// uniqueTags normalizes tags and removes duplicates keeping the order.
+func uniqueTags(tags []string) []string {
+	unique := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		unique = append(unique, tag)
+	}
+
+	return unique
+}
+
+// changeCompanyTags runs change of the tags in the transaction locking the not deleted company.
+// Tags are part of the company representation, so if any tag is attached or detached,
+// new version of the company is saved and recorded to the history. The company is returned with its tags.
+func changeCompanyTags(
+	ctx context.Context, dbConn *sqlx.DB, companyID uuid.UUID, change func(tx *sqlx.Tx) (int64, error),
+) (*db.Company, error) {
+	var dbCompany *db.Company
+	err := db.WithTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+		before, err := db.GetCompanyByIDForUpdate(ctx, tx, companyID)
+		if err != nil {
+			return err
+		}
+		if before.DeletedAt != nil {
+			return db.ErrCompanyNotFound
+		}
+		changed, err := change(tx)
+		if err != nil {
+			return err
+		}
+		if changed == 0 {
+			dbCompany = before
+
+			return nil
+		}
+		touched := *before
+		touched.UpdatedAt = NewUpdatedAt()
+		touched.UpdatedBy = authn.Actor(ctx)
+		if err = db.TouchCompany(ctx, tx, &touched); err != nil {
+			return err
+		}
+		if dbCompany, err = db.GetCompanyByID(ctx, tx, companyID); err != nil {
+			return err
+		}
+
+		return recordCompanyChanges(ctx, tx, db.CompanyAuditUpdate, companyChange{Before: before, After: dbCompany})
+	})
+
+	return dbCompany, err
+}
+
+// newTagsResponse keeps empty list of tags in the response.
+func newTagsResponse(tags db.TagNames) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+// parseTagsFilter parses comma separated tags and the match mode of the company filter.
+func parseTagsFilter(rawTags, rawMatch string) ([]string, db.TagsMatch, error) {
+	var tags []string
+	if rawTags != "" {
+		tags = strings.Split(rawTags, ",")
+	}
+
+	return checkTagsFilter(tags, db.TagsMatch(rawMatch))
+}
+
+// checkTagsFilter normalizes tags of the company filter, any is the default match mode.
+func checkTagsFilter(tags []string, match db.TagsMatch) ([]string, db.TagsMatch, error) {
+	switch match {
+	case "":
+		match = db.TagsMatchAny
+	case db.TagsMatchAny, db.TagsMatchAll:
+	default:
+		return nil, "", fmt.Errorf("tags_match must be %s or %s", db.TagsMatchAny, db.TagsMatchAll)
+	}
+	if len(tags) == 0 {
+		return nil, match, nil
+	}
+	tags = uniqueTags(tags)
+	if len(tags) > MaxTagsCount {
+		return nil, "", fmt.Errorf("tags must contain at most %d tags", MaxTagsCount)
+	}
+	for _, tag := range tags {
+		if message := validateTag(tag); message != "" {
+			return nil, "", fmt.Errorf("tag %q %s", tag, message)
+		}
+	}
+
+	return tags, match, nil
+}
+
+func NewTagID() uuid.UUID {
+	return uuid.New()
+}
@@ -0,0 +1,285 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type CompanyTagsSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestCompanyTagsSuite(t *testing.T) {
+	s := new(CompanyTagsSuite)
+	suite.Run(t, s)
+}
+
+func (s *CompanyTagsSuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil).
+		Maybe()
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("tags-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *CompanyTagsSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *CompanyTagsSuite) TestCompanyTags_OK() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// create companies
+	companyIDs := []string{"f1a2b3c4-d5e6-4f7a-8b9c-0d1e2f3a4b5c", "a2b3c4d5-e6f7-4a8b-9c0d-1e2f3a4b5c6d"}
+	for i, companyID := range companyIDs {
+		fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+			return uuid.MustParse(companyID)
+		})
+		body := fmt.Sprintf(`{"name": "ltd", "code": "TAGS-%d", "country": "CY", "type": "Corporation"}`, i)
+		response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies", strings.NewReader(body), metadata)
+		fakePatch.Reset()
+		assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+	}
+
+	// attach tags, duplicates and case are normalized
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/"+companyIDs[0]+"/tags",
+		strings.NewReader(`{"tags": ["Segment:Enterprise", "priority:high", "segment:enterprise"]}`), metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of attach must match")
+	assert.JSONEq(t, `{"data": ["priority:high", "segment:enterprise"]}`, response.Body.String(),
+		"http body of attach must match")
+	response = makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/"+companyIDs[1]+"/tags",
+		strings.NewReader(`{"tags": ["segment:enterprise"]}`), metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of attach must match")
+
+	// list companies having any or all tags
+	testCases := []struct {
+		query       string
+		expectedIDs []string
+	}{
+		{query: "tags=segment:enterprise,priority:high", expectedIDs: companyIDs},
+		{query: "tags=segment:enterprise,priority:high&tags_match=all", expectedIDs: companyIDs[:1]},
+		{query: "tags=priority:low", expectedIDs: []string{}},
+	}
+	for _, tc := range testCases {
+		response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies?sort=name&"+tc.query, nil, metadata)
+		assert.Equal(t, http.StatusOK, response.Code, "http code of list by %s must match", tc.query)
+		gotBody := new(struct {
+			Data CompaniesListResponse `json:"data"`
+		})
+		if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+			t.Fatalf("decode response body failed: %s", err)
+		}
+		gotIDs := make([]string, 0)
+		for _, company := range gotBody.Data {
+			gotIDs = append(gotIDs, company.ID.String())
+		}
+		assert.ElementsMatch(t, tc.expectedIDs, gotIDs, "companies of %s must match", tc.query)
+	}
+
+	// detach the tag, detaching it again succeeds
+	for i := 0; i < 2; i++ {
+		response = makeTestRequest(s.router, http.MethodDelete,
+			"/api/v1/companies/"+companyIDs[0]+"/tags/Priority:High", nil, metadata)
+		assert.Equal(t, http.StatusNoContent, response.Code, "http code of detach must match")
+	}
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyIDs[0], nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotCompany := new(struct {
+		Data GetCompanyResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotCompany); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	assert.Equal(t, []string{"segment:enterprise"}, gotCompany.Data.Tags, "tags must match")
+
+	// list tags
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/tags", nil, metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of list tags must match")
+	gotTags := new(struct {
+		Data TagsResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotTags); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	gotCounts := make(map[string]int)
+	for _, tag := range gotTags.Data {
+		gotCounts[tag.Name] = tag.CompaniesCount
+	}
+	assert.Equal(t, map[string]int{"priority:high": 0, "segment:enterprise": 2}, gotCounts, "tags must match")
+}
+
+func (s *CompanyTagsSuite) TestCompanyTags_Version() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// create the company
+	companyID := "c3d4e5f6-a7b8-4c9d-8e0f-1a2b3c4d5e6f"
+	fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+		return uuid.MustParse(companyID)
+	})
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "TAGS-VERSION", "country": "CY", "type": "Corporation"}`), metadata)
+	fakePatch.Reset()
+	assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+
+	// only attached and detached tags make new version
+	testCases := []struct {
+		method       string
+		path         string
+		body         string
+		expectedETag string
+	}{
+		{method: http.MethodPost, path: "/tags", body: `{"tags": ["vip"]}`, expectedETag: `"2"`},
+		{method: http.MethodPost, path: "/tags", body: `{"tags": ["vip"]}`, expectedETag: `"2"`},
+		{method: http.MethodDelete, path: "/tags/vip", expectedETag: `"3"`},
+		{method: http.MethodDelete, path: "/tags/vip", expectedETag: `"3"`},
+	}
+	for _, tc := range testCases {
+		response = makeTestRequest(s.router, tc.method, "/api/v1/companies/"+companyID+tc.path,
+			strings.NewReader(tc.body), metadata)
+		assert.Less(t, response.Code, http.StatusMultipleChoices, "http code of %s %s must match", tc.method, tc.path)
+		response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyID, nil, metadata)
+		assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+		assert.Equal(t, tc.expectedETag, response.Header().Get("ETag"), "etag after %s %s must match", tc.method, tc.path)
+	}
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyID+"/history", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code of history must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data CompanyHistoryResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if !assert.Len(t, gotBody.Data, 3, "records count must match") {
+		return
+	}
+	expectedChanges := [][]CompanyFieldChange{
+		{{Field: "tags", Old: []any{"vip"}, New: []any{}}},
+		{{Field: "tags", Old: []any{}, New: []any{"vip"}}},
+	}
+	for i, expected := range expectedChanges {
+		assert.Equal(t, db.CompanyAuditUpdate, gotBody.Data[i].Action, "action must match")
+		assert.Equal(t, "test/tags-tester", gotBody.Data[i].Actor, "actor must match")
+		assert.Equal(t, expected, gotBody.Data[i].Changes, "changes must match")
+	}
+}
+
+func (s *CompanyTagsSuite) TestCompanyTags_Invalid() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/"+uuid.New().String()+"/tags",
+		strings.NewReader(`{"tags": ["vip", "high priority"]}`), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code must match")
+
+	// assert HTTP body
+	expectedBody := `{
+	"error": "invalid tags",
+	"details": [
+		{
+			"field": "tags[1]",
+			"message": "must contain only latin letters, digits and _.:- starting with a letter or a digit"
+		}
+	]
+}`
+	assert.JSONEq(t, expectedBody, response.Body.String(), "http body must match")
+}
+
+func TestParseTagsFilter(t *testing.T) {
+	testCases := []struct {
+		rawTags       string
+		rawMatch      string
+		expectedTags  []string
+		expectedMatch db.TagsMatch
+		expectedErr   string
+	}{
+		{rawTags: "", rawMatch: "", expectedTags: nil, expectedMatch: db.TagsMatchAny},
+		{
+			rawTags:       "VIP, vip,segment:smb",
+			rawMatch:      "all",
+			expectedTags:  []string{"vip", "segment:smb"},
+			expectedMatch: db.TagsMatchAll,
+		},
+		{rawTags: "vip", rawMatch: "none", expectedErr: "tags_match must be any or all"},
+		{rawTags: "vip,", rawMatch: "any", expectedErr: `tag "" is required`},
+	}
+	for _, tc := range testCases {
+		gotTags, gotMatch, err := parseTagsFilter(tc.rawTags, tc.rawMatch)
+		if tc.expectedErr != "" {
+			assert.EqualError(t, err, tc.expectedErr, "error of %q must match", tc.rawTags)
+
+			continue
+		}
+		if assert.NoError(t, err, "tags %q must be parsed", tc.rawTags) {
+			assert.Equal(t, tc.expectedTags, gotTags, "tags of %q must match", tc.rawTags)
+			assert.Equal(t, tc.expectedMatch, gotMatch, "match of %q must match", tc.rawTags)
+		}
+	}
+}
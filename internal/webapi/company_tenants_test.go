@@ -0,0 +1,170 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type CompanyTenantsSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+	otherTenantJWT      string
+}
+
+func TestCompanyTenantsSuite(t *testing.T) {
+	s := new(CompanyTenantsSuite)
+	suite.Run(t, s)
+}
+
+func (s *CompanyTenantsSuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil).
+		Maybe()
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("tenants-tester"), authn.WithTenant("acme"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	otherTenantJWT, err := tokenService.IssueToken(authn.WithSubject("tenants-tester"), authn.WithTenant("globex"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.otherTenantJWT = otherTenantJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *CompanyTenantsSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *CompanyTenantsSuite) TestCompanyTenants_Isolation() {
+	t := s.T()
+
+	companyIDs := []string{"b1c2d3e4-f5a6-4b7c-8d9e-0f1a2b3c4d5e", "c2d3e4f5-a6b7-4c8d-9e0f-1a2b3c4d5e6f"}
+	tenantsJWT := []string{s.testJWT, s.otherTenantJWT}
+	metadata := make([]*testRequestMetaData, 0, len(tenantsJWT))
+	for _, tenantJWT := range tenantsJWT {
+		metadata = append(metadata, &testRequestMetaData{
+			remoteAddr: "127.0.0.1:63099",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", tenantJWT),
+			},
+		})
+	}
+
+	// both tenants create the company with the same code and country
+	for i, companyID := range companyIDs {
+		fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+			return uuid.MustParse(companyID)
+		})
+		response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+			strings.NewReader(`{"name": "ltd", "code": "TENANTS", "country": "CY", "type": "Corporation"}`), metadata[i])
+		fakePatch.Reset()
+		assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+	}
+
+	// the company of another tenant is not found
+	response := makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyIDs[0], nil, metadata[1])
+	assert.Equal(t, http.StatusNotFound, response.Code, "http code of get by another tenant must match")
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyIDs[0], nil,
+		&testRequestMetaData{remoteAddr: "127.0.0.1:63099"})
+	assert.Equal(t, http.StatusNotFound, response.Code, "http code of get by anonymous client must match")
+	response = makeTestRequest(s.router, http.MethodDelete, "/api/v1/companies/"+companyIDs[0], nil, metadata[1])
+	assert.Equal(t, http.StatusNotFound, response.Code, "http code of delete by another tenant must match")
+	response = makeTestRequest(s.router, http.MethodPut, "/api/v1/companies/"+companyIDs[1]+"/parent",
+		strings.NewReader(`{"parent_id": "`+companyIDs[0]+`"}`), metadata[1])
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code of set parent of another tenant must match")
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies?code_prefix=TENANTS", nil, metadata[0])
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data []CompanyResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if assert.Len(t, gotBody.Data, 1, "companies count must match") {
+		assert.Equal(t, companyIDs[0], gotBody.Data[0].ID.String(), "company id must match")
+	}
+
+	// the company of the tenant is not changed
+	var tenantID string
+	err := s.dbConn.Get(&tenantID, `SELECT tenant_id FROM companies WHERE id = $1 AND deleted_at IS NULL`, companyIDs[0])
+	if assert.NoError(t, err, "select company must succeed") {
+		assert.Equal(t, "acme", tenantID, "tenant must match")
+	}
+}
+
+func (s *CompanyTenantsSuite) TestCompanyTenants_LastEventID() {
+	t := s.T()
+	ctx := logging.WithContext(context.Background(), s.logger)
+
+	// events of the second tenant are written after the events of the first one
+	tenantIDs := []string{"last-event-first", "last-event-second"}
+	lastIDs := make([]int64, 0, len(tenantIDs))
+	for _, tenantID := range tenantIDs {
+		tenantCtx := db.WithTenantID(ctx, tenantID)
+		err := db.WithTx(tenantCtx, s.dbConn, func(tx *sqlx.Tx) error {
+			return db.InsertCompanyEvents(tenantCtx, tx, []db.CompanyEvent{
+				{Type: db.CompanyCreated, CompanyID: uuid.New(), Payload: []byte(`{}`), CreatedAt: time.Now().UTC()},
+			})
+		})
+		if err != nil {
+			t.Fatalf("insert company events failed: %s", err)
+		}
+		var lastID int64
+		err = s.dbConn.Get(&lastID, `SELECT max(id) FROM company_outbox WHERE tenant_id = $1`, tenantID)
+		if err != nil {
+			t.Fatalf("select last event id failed: %s", err)
+		}
+		lastIDs = append(lastIDs, lastID)
+	}
+
+	// the last event of the tenant is not the last one of all tenants
+	for i, tenantID := range tenantIDs {
+		gotLastID, err := db.GetLastCompanyEventID(db.WithTenantID(ctx, tenantID), s.dbConn)
+		if assert.NoError(t, err, "get last event id must succeed") {
+			assert.Equal(t, lastIDs[i], gotLastID, "last event id of %s must match", tenantID)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package webapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// getCompanyForWrite fetches the company which is going to be changed
+// and checks If-Match precondition against it.
+// If the company can not be changed, the response is written and false is returned.
+func getCompanyForWrite(
+	ctx context.Context, w http.ResponseWriter, r *http.Request, dbConn db.RowxQueryerContext, companyID uuid.UUID,
+) (*db.Company, bool) {
+	logger := logging.FromContext(ctx)
+	dbCompany, err := db.GetCompanyByID(ctx, dbConn, companyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Warn("get company failed")
+		if errors.Is(err, sql.ErrNoRows) {
+			NotFound(ctx, w, "company not found")
+
+			return nil, false
+		}
+		InternalServerError(ctx, w, "get company failed")
+
+		return nil, false
+	}
+
+	if !ifMatch(r, companyETag(dbCompany.Version)) {
+		logger.WithField("company_id", companyID).Warn("company etag mismatch")
+		PreconditionFailed(ctx, w, "company was modified")
+
+		return nil, false
+	}
+
+	return dbCompany, true
+}
+
+type CompanyConflictDetails struct {
+	ExistingCompanyID *uuid.UUID `json:"existing_company_id,omitempty"`
+}
+
+// companyConflict responds with 409 if err is db.CompanyConflictError and reports whether it did.
+// The company written concurrently is looked up by dbConn, it must be called after the transaction of the write.
+func companyConflict(ctx context.Context, w http.ResponseWriter, dbConn db.RowxQueryerContext, err error) bool {
+	var conflictErr *db.CompanyConflictError
+	if !errors.As(err, &conflictErr) {
+		return false
+	}
+	existingCompanyID := conflictErr.ExistingCompanyID
+	if existingCompanyID == uuid.Nil && conflictErr.Company != nil {
+		var getErr error
+		existingCompanyID, getErr = db.GetConflictingCompanyID(ctx, dbConn, conflictErr.Company)
+		if getErr != nil {
+			// the conflict is reported anyway, only without the ID
+			logging.FromContext(ctx).WithError(getErr).Warn("get conflicting company failed")
+		}
+	}
+	details := new(CompanyConflictDetails)
+	if existingCompanyID != uuid.Nil {
+		details.ExistingCompanyID = &existingCompanyID
+	}
+	Conflict(ctx, w, "company with the same code and country already exists", details)
+
+	return true
+}
+
+// companyCode is the key of the company code in the country, countries are compared case-insensitively.
+func companyCode(code, country string) db.CompanyCode {
+	return db.CompanyCode{Code: code, Country: strings.ToUpper(country)}
+}
+
+// existingCompanyIDs returns IDs of not deleted companies having the codes.
+func existingCompanyIDs(
+	ctx context.Context, dbConn sqlx.QueryerContext, codes []db.CompanyCode,
+) (map[db.CompanyCode]uuid.UUID, error) {
+	existingCompanies, err := db.GetCompaniesByCodes(ctx, dbConn, codes)
+	if err != nil {
+		return nil, err
+	}
+	existingIDs := make(map[db.CompanyCode]uuid.UUID, len(existingCompanies))
+	for i := range existingCompanies {
+		existingIDs[companyCode(existingCompanies[i].Code, existingCompanies[i].Country)] = existingCompanies[i].ID
+	}
+
+	return existingIDs, nil
+}
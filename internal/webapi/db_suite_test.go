@@ -0,0 +1,214 @@
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-testfixtures/testfixtures/v3"
+	"github.com/jmoiron/sqlx"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/pzabolotniy/logging/pkg/logging"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/migration"
+)
+
+const (
+	testDBUser    = "companies_db"
+	testDBName    = "test_companies_db"
+	testDBSSLMode = "disable"
+)
+
+// testPostgres is the database server shared by all suites of the package.
+// It is started by the first suite, migrations are applied once to testDBName
+// and every suite gets its own copy of it, so suites do not see data of each other.
+var testPostgres struct {
+	once      sync.Once
+	err       error
+	pool      *dockertest.Pool
+	resource  *dockertest.Resource
+	adminConn *sqlx.DB
+	host      string
+	port      string
+	databases int32
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	purgeTestPostgres()
+	os.Exit(code)
+}
+
+// dbSuite is embedded by the suites running against the database.
+type dbSuite struct {
+	suite.Suite
+	dbName  string
+	dbConn  *sqlx.DB
+	logger  logging.Logger
+	appConf *config.App
+}
+
+func (s *dbSuite) SetupSuite() {
+	t := s.T()
+	ctx := context.Background()
+	logger := logging.GetLogger()
+	ctx = logging.WithContext(ctx, logger)
+
+	testPostgres.once.Do(func() {
+		testPostgres.err = startTestPostgres(ctx)
+	})
+	if testPostgres.err != nil {
+		t.Fatalf("Could not start pgResource: %s", testPostgres.err)
+	}
+
+	dbName := fmt.Sprintf("%s_%d", testDBName, atomic.AddInt32(&testPostgres.databases, 1))
+	_, err := testPostgres.adminConn.Exec(fmt.Sprintf(`CREATE DATABASE %s TEMPLATE %s`, dbName, testDBName))
+	if err != nil {
+		t.Fatalf("create database failed: '%s'", err)
+	}
+	dbConf := testDBConf(dbName)
+	dbConn, err := db.Connect(ctx, dbConf)
+	if err != nil {
+		t.Fatalf("connect failed: '%s'", err)
+	}
+
+	appConf := &config.App{
+		DB:     dbConf,
+		WebAPI: &config.WebAPI{Listen: ""},
+		GeoIP: &config.GeoIP{
+			AllowedCountryName: "localhost",
+			EndPoint:           "http://ip.me",
+			Timeout:            10 * time.Second,
+		},
+		ClientToken: &config.ClientToken{
+			TTL:    1 * time.Hour,
+			Issuer: "test",
+			Secret: "secret",
+		},
+	}
+
+	s.dbName = dbName
+	s.dbConn = dbConn
+	s.logger = logger
+	s.appConf = appConf
+}
+
+func (s *dbSuite) TearDownSuite() {
+	t := s.T()
+	dbConn := s.dbConn
+	if disconnectErr := db.Disconnect(dbConn); disconnectErr != nil {
+		t.Fatalf("disconnect failed: '%s'", disconnectErr)
+	}
+	if _, dropErr := testPostgres.adminConn.Exec(`DROP DATABASE ` + s.dbName); dropErr != nil {
+		t.Fatalf("drop database failed: '%s'", dropErr)
+	}
+}
+
+// loadFixtures loads the fixture files into the database of the suite.
+func (s *dbSuite) loadFixtures(files ...string) {
+	t := s.T()
+	fixtures, err := testfixtures.New(
+		testfixtures.Database(s.dbConn.DB),
+		testfixtures.Dialect("postgres"),
+		testfixtures.Files(files...),
+	)
+	if err != nil {
+		t.Fatalf("prepare fixtures failed: %s", err)
+	}
+	err = fixtures.Load()
+	if err != nil {
+		t.Fatalf("load fixtures failed: %s", err)
+	}
+}
+
+// startTestPostgres runs the container and applies migrations to the template database of the suites.
+func startTestPostgres(ctx context.Context) error {
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		return fmt.Errorf("could not connect to docker: %w", err)
+	}
+	// pulls an image, creates a container based on it and runs it
+	pgResource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "14.5",
+		Env: []string{
+			fmt.Sprintf("POSTGRES_USER=%s", testDBUser),
+			fmt.Sprintf("POSTGRES_DB=%s", testDBName),
+			"POSTGRES_HOST_AUTH_METHOD=trust",
+			"listen_addresses = '*'",
+		},
+		Cmd: []string{"postgres", "-c", "log_statement=all", "-c", "log_destination=stderr"},
+	}, func(config *docker.HostConfig) {
+		// set AutoRemove to true so that stopped container goes away by itself
+		config.AutoRemove = true
+		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		return err
+	}
+	testPostgres.pool = pool
+	testPostgres.resource = pgResource
+	hostAndPort := pgResource.GetHostPort("5432/tcp")
+	testPostgres.host, testPostgres.port, err = net.SplitHostPort(hostAndPort)
+	if err != nil {
+		return fmt.Errorf("split host-port '%s' failed: %w", hostAndPort, err)
+	}
+
+	var templateConn *sqlx.DB
+	pool.MaxWait = 30 * time.Second
+	if err = pool.Retry(func() error {
+		templateConn, err = db.Connect(ctx, testDBConf(testDBName))
+
+		return err
+	}); err != nil {
+		return fmt.Errorf("could not connect to docker: %w", err)
+	}
+	err = migration.MigrateUp(ctx, templateConn, testDBConf(testDBName))
+	if err != nil {
+		return fmt.Errorf("apply migrations failed: %w", err)
+	}
+	// the template can not be copied while it is connected
+	if err = db.Disconnect(templateConn); err != nil {
+		return fmt.Errorf("disconnect failed: %w", err)
+	}
+	testPostgres.adminConn, err = db.Connect(ctx, testDBConf("postgres"))
+	if err != nil {
+		return fmt.Errorf("connect failed: %w", err)
+	}
+
+	return nil
+}
+
+func testDBConf(dbName string) *config.DB {
+	return &config.DB{
+		ConnString: fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=%s",
+			testPostgres.host, testPostgres.port, testDBUser, dbName, testDBSSLMode),
+		MigrationDir:    "../../sql-migrations",
+		MigrationTable:  "migrations",
+		MaxOpenConns:    20,
+		ConnMaxLifetime: 10 * time.Second,
+	}
+}
+
+func purgeTestPostgres() {
+	logger := logging.GetLogger()
+	if testPostgres.adminConn != nil {
+		if err := db.Disconnect(testPostgres.adminConn); err != nil {
+			logger.WithError(err).Error("disconnect failed")
+		}
+	}
+	if testPostgres.resource != nil {
+		if err := testPostgres.pool.Purge(testPostgres.resource); err != nil {
+			logger.WithError(err).Error("purge pgResource failed")
+		}
+	}
+}
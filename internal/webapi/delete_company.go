@@ -1,12 +1,16 @@
 package webapi
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/pzabolotniy/logging/pkg/logging"
 
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
 )
 
@@ -24,13 +28,49 @@ func (h *HandlerEnv) DeleteCompany(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = db.DeleteCompanyByID(ctx, dbConn, companyID)
+	// without If-Match deleting of already deleted company succeeds
+	checkVersion := r.Header.Get("If-Match") != ""
+	deletedAt := NewUpdatedAt()
+	deletedBy := authn.Actor(ctx)
+	err = db.WithTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+		before, getErr := db.GetCompanyByIDForUpdate(ctx, tx, companyID)
+		if getErr != nil {
+			return getErr
+		}
+		if checkVersion {
+			if before.DeletedAt != nil {
+				return db.ErrCompanyNotFound
+			}
+			if !ifMatch(r, companyETag(before.Version)) {
+				return db.ErrCompanyVersionMismatch
+			}
+		}
+		if before.DeletedAt != nil {
+			return nil
+		}
+		deleteErr := db.DeleteCompanyByIDAndVersion(ctx, tx, companyID, before.Version, deletedAt, deletedBy)
+		if deleteErr != nil {
+			return deleteErr
+		}
+
+		return recordCompanyChanges(ctx, tx, db.CompanyAuditDelete, companyChange{
+			Before: before,
+			After:  deletedCompany(before, deletedAt, deletedBy),
+		})
+	})
 	if err != nil {
 		logger.WithError(err).WithField("company_id", companyID).Warn("delete company failed")
-		InternalServerError(ctx, w, "delete company failed")
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			NotFound(ctx, w, "company not found")
+		case errors.Is(err, db.ErrCompanyVersionMismatch):
+			PreconditionFailed(ctx, w, "company was modified")
+		default:
+			InternalServerError(ctx, w, "delete company failed")
+		}
 
 		return
 	}
 
-	OKResponse(ctx, w, nil)
+	NoContentResponse(w)
 }
@@ -0,0 +1,33 @@
+package webapi
+
+import (
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+func (h *HandlerEnv) DeleteCompanyAddress(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	companyID, addressID, ok := parseAddressURLParams(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	err := withCompanyLocked(ctx, dbConn, companyID, func(tx *sqlx.Tx) error {
+		return db.DeleteCompanyAddress(ctx, tx, companyID, addressID)
+	})
+	if err != nil {
+		logger.WithError(err).WithField("address_id", addressID).Error("delete address failed")
+		addressWriteFailed(ctx, w, err)
+
+		return
+	}
+
+	NoContentResponse(w)
+}
@@ -0,0 +1,33 @@
+package webapi
+
+import (
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+func (h *HandlerEnv) DeleteCompanyContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	companyID, contactID, ok := parseContactURLParams(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	err := withCompanyLocked(ctx, dbConn, companyID, func(tx *sqlx.Tx) error {
+		return db.DeleteCompanyContact(ctx, tx, companyID, contactID)
+	})
+	if err != nil {
+		logger.WithError(err).WithField("contact_id", contactID).Error("delete contact failed")
+		contactWriteFailed(ctx, w, err)
+
+		return
+	}
+
+	NoContentResponse(w)
+}
@@ -0,0 +1,60 @@
+package webapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// DeleteCompanyTag detaches the tag from the company, detaching not attached tag succeeds without new version.
+func (h *HandlerEnv) DeleteCompanyTag(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return
+	}
+	tag := normalizeTag(chi.URLParam(r, "tag"))
+	if message := validateTag(tag); message != "" {
+		logger.WithField("tag", tag).Warn("invalid tag")
+		BadRequest(ctx, w, "tag "+message)
+
+		return
+	}
+
+	_, err = changeCompanyTags(ctx, dbConn, companyID, func(tx *sqlx.Tx) (int64, error) {
+		return db.DetachCompanyTag(ctx, tx, companyID, tag)
+	})
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("detach tag failed")
+		companyTagsWriteFailed(ctx, w, err)
+
+		return
+	}
+
+	NoContentResponse(w)
+}
+
+// companyTagsWriteFailed responds to the failed change of the company tags.
+func companyTagsWriteFailed(ctx context.Context, w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		NotFound(ctx, w, "company not found")
+
+		return
+	}
+	InternalServerError(ctx, w, "save tags failed")
+}
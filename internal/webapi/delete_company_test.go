@@ -164,20 +164,119 @@ func (s *DeleteCompanySuite) TestDeleteCompanySuite_OK() {
 	if err != nil {
 		t.Fatalf("read response body failed: %s", err)
 	}
-	expectedHTTPCode := http.StatusOK
+	expectedHTTPCode := http.StatusNoContent
 	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
 
 	// assert HTTP body
-	expectedHTTPBody := `{}`
+	assert.Empty(t, gotBody, "body must be empty")
+
+	dbCompany := selectDbCompanyByID(t, s.dbConn, companyID)
+	assert.NotNil(t, dbCompany.DeletedAt, "company must be soft deleted")
+}
+
+func (s *DeleteCompanySuite) TestDeleteCompanySuite_AlreadyDeleted() {
+	t := s.T()
+
+	// testdata
+	companyID := "5b6e7620-808f-4c9a-887c-56fe5290f537"
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodDelete, testURL, nil, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusNoContent
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	assert.Empty(t, gotBody, "body must be empty")
+
+	dbCompany := selectDbCompanyByID(t, s.dbConn, companyID)
+	assert.Equal(t, int64(2), dbCompany.Version, "deleted company must not change")
+}
+
+func (s *DeleteCompanySuite) TestDeleteCompanySuite_NotFound() {
+	t := s.T()
+
+	// testdata
+	companyID := "5b6e7620-808f-4c9a-887c-56fe5290f999"
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodDelete, testURL, nil, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusNotFound
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "company not found"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *DeleteCompanySuite) TestDeleteCompanySuite_PreconditionFailed() {
+	t := s.T()
+
+	// testdata
+	companyID := "5b6e7620-808f-4c9a-887c-56fe5290f536"
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"If-Match":      `"42"`,
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodDelete, testURL, nil, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusPreconditionFailed
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "company was modified"
+}`
 	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
 
 	dbCompany := selectDbCompanyByID(t, s.dbConn, companyID)
-	assert.Nil(t, dbCompany, "companyID should be nil")
+	assert.Nil(t, dbCompany.DeletedAt, "company must not be deleted")
 }
 
 func selectDbCompanyByID(t *testing.T, dbConn *sqlx.DB, companyID string) *db.Company {
 	dbCompany := new(db.Company)
-	err := dbConn.QueryRowx(`SELECT id, name, code, country, website, phone, created_at FROM companies WHERE id = $1`, companyID).StructScan(dbCompany)
+	err := dbConn.QueryRowx(`SELECT id, name, code, country, website, phone, description, employees_count, registered, type, created_at, updated_at, version, deleted_at FROM companies WHERE id = $1`, companyID).StructScan(dbCompany)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil
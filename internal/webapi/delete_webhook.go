@@ -0,0 +1,52 @@
+package webapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// DeleteWebhook unregisters the webhook, its pending deliveries are not delivered anymore.
+// Only the client registered the webhook and admins can delete it.
+func (h *HandlerEnv) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlWebhookID := chi.URLParam(r, "webhookID")
+	webhookID, err := uuid.Parse(urlWebhookID)
+	if err != nil {
+		logger.WithError(err).WithField("webhook_id", urlWebhookID).Warn("parse webhookID failed")
+		BadRequest(ctx, w, "invalid webhookID")
+
+		return
+	}
+
+	subscription, err := db.GetWebhookSubscription(ctx, dbConn, webhookID)
+	if err == nil && !isWebhookVisible(ctx, subscription) {
+		// webhooks of other clients are not disclosed
+		logger.WithField("webhook_id", webhookID).Warn("delete of webhook of another client")
+		err = db.ErrWebhookNotFound
+	}
+	if err == nil {
+		err = db.DeleteWebhookSubscription(ctx, dbConn, webhookID)
+	}
+	if err != nil {
+		logger.WithError(err).WithField("webhook_id", webhookID).Warn("delete webhook failed")
+		if errors.Is(err, db.ErrWebhookNotFound) {
+			NotFound(ctx, w, "webhook not found")
+
+			return
+		}
+		InternalServerError(ctx, w, "delete webhook failed")
+
+		return
+	}
+
+	NoContentResponse(w)
+}
@@ -0,0 +1,29 @@
+package webapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// companyETag is a strong entity tag built from the company version.
+func companyETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ifMatch reports whether the If-Match precondition of the request holds for the etag.
+// Request without If-Match has no precondition.
+func ifMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
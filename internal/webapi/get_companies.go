@@ -0,0 +1,203 @@
+package webapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+const (
+	DefaultCompaniesPageSize = 20
+	MaxCompaniesPageSize     = 100
+	DefaultCompaniesSort     = "-created_at"
+)
+
+var errCursorSortMismatch = errors.New("cursor was issued for another sort")
+
+type CompaniesListResponse []CompanyResponse
+
+// companiesCursor points to the last company of the page.
+type companiesCursor struct {
+	Sort      string    `json:"sort"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// GetCompanies returns the page of companies matching the filter,
+// use next_cursor of the response to get the next page.
+func (h *HandlerEnv) GetCompanies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	query := r.URL.Query()
+	params, err := parseCompaniesListQuery(query)
+	if err != nil {
+		logger.WithError(err).Warn("parse query failed")
+		BadRequest(ctx, w, err.Error())
+
+		return
+	}
+	if !canSeeDeleted(ctx, w, params.Filter.WithDeleted) {
+		return
+	}
+
+	// one more company is requested to know whether the next page exists
+	pageSize := params.Limit
+	params.Limit++
+	dbCompanies, err := db.ListCompanies(ctx, dbConn, params)
+	if err != nil {
+		logger.WithError(err).Error("list companies failed")
+		InternalServerError(ctx, w, "list companies failed")
+
+		return
+	}
+
+	nextCursor := ""
+	if len(dbCompanies) > pageSize {
+		dbCompanies = dbCompanies[:pageSize]
+		nextCursor, err = encodeCompaniesCursor(sortParam(query), &dbCompanies[pageSize-1])
+		if err != nil {
+			logger.WithError(err).Error("encode cursor failed")
+			InternalServerError(ctx, w, "list companies failed")
+
+			return
+		}
+	}
+
+	response := make(CompaniesListResponse, 0, len(dbCompanies))
+	for i := range dbCompanies {
+		response = append(response, newCompanyResponse(&dbCompanies[i]))
+	}
+	OKPageResponse(ctx, w, response, nextCursor)
+}
+
+func parseCompaniesListQuery(query url.Values) (*db.CompanyListParams, error) {
+	filter, err := parseCompanyFilter(query)
+	if err != nil {
+		return nil, err
+	}
+	params := &db.CompanyListParams{
+		Filter: *filter,
+		Limit:  DefaultCompaniesPageSize,
+	}
+
+	sort := sortParam(query)
+	params.Sort.Desc = strings.HasPrefix(sort, "-")
+	switch db.CompanySortField(strings.TrimPrefix(sort, "-")) {
+	case db.SortByCreatedAt:
+		params.Sort.Field = db.SortByCreatedAt
+	case db.SortByName:
+		params.Sort.Field = db.SortByName
+	default:
+		return nil, fmt.Errorf("invalid sort: %q", sort)
+	}
+
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		params.Limit, err = strconv.Atoi(rawLimit)
+		if err != nil || params.Limit < 1 || params.Limit > MaxCompaniesPageSize {
+			return nil, fmt.Errorf("limit must be from 1 to %d", MaxCompaniesPageSize)
+		}
+	}
+
+	if rawCursor := query.Get("cursor"); rawCursor != "" {
+		params.After, err = decodeCompaniesCursor(rawCursor, sort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+	}
+
+	return params, nil
+}
+
+func parseCompanyFilter(query url.Values) (*db.CompanyFilter, error) {
+	filter := &db.CompanyFilter{
+		Country:        query.Get("country"),
+		CodePrefix:     query.Get("code_prefix"),
+		NameContains:   query.Get("name"),
+		AddressCity:    query.Get("address_city"),
+		AddressCountry: query.Get("address_country"),
+		WithDeleted:    query.Get("include_deleted") == "true",
+	}
+	var err error
+	filter.Tags, filter.TagsMatch, err = parseTagsFilter(query.Get("tags"), query.Get("tags_match"))
+	if err != nil {
+		return nil, err
+	}
+	timeParams := []struct {
+		name   string
+		target **time.Time
+	}{
+		{name: "created_from", target: &filter.CreatedFrom},
+		{name: "created_to", target: &filter.CreatedTo},
+	}
+	for _, param := range timeParams {
+		rawTime := query.Get(param.name)
+		if rawTime == "" {
+			continue
+		}
+		parsedTime, err := time.Parse(time.RFC3339, rawTime)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be RFC3339 time", param.name)
+		}
+		parsedTime = parsedTime.UTC()
+		*param.target = &parsedTime
+	}
+
+	return filter, nil
+}
+
+func sortParam(query url.Values) string {
+	if sort := query.Get("sort"); sort != "" {
+		return sort
+	}
+
+	return DefaultCompaniesSort
+}
+
+func encodeCompaniesCursor(sort string, lastCompany *db.Company) (string, error) {
+	cursor := &companiesCursor{
+		Sort:      sort,
+		CreatedAt: lastCompany.CreatedAt,
+		Name:      lastCompany.Name,
+		ID:        lastCompany.ID,
+	}
+	encodedCursor, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor failed: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encodedCursor), nil
+}
+
+func decodeCompaniesCursor(rawCursor, sort string) (*db.Company, error) {
+	encodedCursor, err := base64.RawURLEncoding.DecodeString(rawCursor)
+	if err != nil {
+		return nil, fmt.Errorf("decode base64 failed: %w", err)
+	}
+	cursor := new(companiesCursor)
+	if err = json.Unmarshal(encodedCursor, cursor); err != nil {
+		return nil, fmt.Errorf("decode json failed: %w", err)
+	}
+	if cursor.Sort != sort {
+		return nil, errCursorSortMismatch
+	}
+
+	return &db.Company{
+		CreatedAt: cursor.CreatedAt,
+		Name:      cursor.Name,
+		ID:        cursor.ID,
+	}, nil
+}
@@ -0,0 +1,188 @@
+package webapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+type CompaniesExportFormat string
+
+const (
+	ExportFormatCSV    CompaniesExportFormat = "csv"
+	ExportFormatNDJSON CompaniesExportFormat = "ndjson"
+)
+
+// CompaniesExportBatchSize is the number of companies fetched from the cursor at once.
+const CompaniesExportBatchSize = 1000
+
+// companiesExportColumns are the CSV columns, they are named as JSON fields of CompanyResponse.
+// Tags are written comma separated, as they are passed to the tags filter.
+var companiesExportColumns = []string{
+	"id", "name", "code", "country", "website", "phone", "description", "employees_count", "registered", "type",
+	"created_at", "updated_at", "version", "deleted_at", "parent_id", "tags", "created_by", "updated_by",
+}
+
+// companiesWriter writes exported companies in one of the formats.
+type companiesWriter interface {
+	writeHeader() error
+	write(dbCompany *db.Company) error
+	flush() error
+}
+
+// GetCompaniesExport streams all companies matching the filter of GetCompanies in the order of IDs
+// as CSV or NDJSON (one JSON company per line).
+// If reading fails after the response started, the connection is aborted, so the export is never silently truncated.
+func (h *HandlerEnv) GetCompaniesExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	query := r.URL.Query()
+	format := CompaniesExportFormat(query.Get("format"))
+	var writer companiesWriter
+	var contentType string
+	switch format {
+	case ExportFormatCSV:
+		writer = &csvCompaniesWriter{writer: csv.NewWriter(w)}
+		contentType = "text/csv; charset=utf-8"
+	case ExportFormatNDJSON:
+		writer = &ndjsonCompaniesWriter{encoder: json.NewEncoder(w)}
+		contentType = "application/x-ndjson"
+	default:
+		logger.WithField("format", format).Warn("invalid export format")
+		BadRequest(ctx, w, fmt.Sprintf("format must be %s or %s", ExportFormatCSV, ExportFormatNDJSON))
+
+		return
+	}
+	filter, err := parseCompanyFilter(query)
+	if err != nil {
+		logger.WithError(err).Warn("parse query failed")
+		BadRequest(ctx, w, err.Error())
+
+		return
+	}
+	if !canSeeDeleted(ctx, w, filter.WithDeleted) {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="companies.%s"`, format))
+		w.WriteHeader(http.StatusOK)
+
+		return writer.writeHeader()
+	}
+	exported := 0
+	err = db.ExportCompanies(ctx, dbConn, filter, CompaniesExportBatchSize, func(dbCompanies []db.Company) error {
+		if !started {
+			if startErr := start(); startErr != nil {
+				return startErr
+			}
+		}
+		for i := range dbCompanies {
+			if writeErr := writer.write(&dbCompanies[i]); writeErr != nil {
+				return writeErr
+			}
+		}
+		if flushErr := writer.flush(); flushErr != nil {
+			return flushErr
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		exported += len(dbCompanies)
+
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+		if err == nil {
+			err = writer.flush()
+		}
+	}
+	if err != nil {
+		logger.WithError(err).WithField("exported", exported).Error("export companies failed")
+		if !started {
+			InternalServerError(ctx, w, "export companies failed")
+
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+	logger.WithField("exported", exported).Trace("companies exported")
+}
+
+type csvCompaniesWriter struct {
+	writer *csv.Writer
+}
+
+func (cw *csvCompaniesWriter) writeHeader() error {
+	return cw.writer.Write(companiesExportColumns)
+}
+
+func (cw *csvCompaniesWriter) write(dbCompany *db.Company) error {
+	deletedAt := ""
+	if dbCompany.DeletedAt != nil {
+		deletedAt = dbCompany.DeletedAt.Format(time.RFC3339Nano)
+	}
+	parentID := ""
+	if dbCompany.ParentID != nil {
+		parentID = dbCompany.ParentID.String()
+	}
+
+	return cw.writer.Write([]string{
+		dbCompany.ID.String(),
+		dbCompany.Name,
+		dbCompany.Code,
+		dbCompany.Country,
+		dbCompany.WebSite,
+		dbCompany.Phone,
+		dbCompany.Description,
+		strconv.Itoa(dbCompany.EmployeesCount),
+		strconv.FormatBool(dbCompany.Registered),
+		string(dbCompany.Type),
+		dbCompany.CreatedAt.Format(time.RFC3339Nano),
+		dbCompany.UpdatedAt.Format(time.RFC3339Nano),
+		strconv.FormatInt(dbCompany.Version, 10),
+		deletedAt,
+		parentID,
+		strings.Join(dbCompany.Tags, ","),
+		dbCompany.CreatedBy,
+		dbCompany.UpdatedBy,
+	})
+}
+
+func (cw *csvCompaniesWriter) flush() error {
+	cw.writer.Flush()
+
+	return cw.writer.Error()
+}
+
+type ndjsonCompaniesWriter struct {
+	encoder *json.Encoder
+}
+
+func (nw *ndjsonCompaniesWriter) writeHeader() error {
+	return nil
+}
+
+// write encodes the company as JSON followed by the new line.
+func (nw *ndjsonCompaniesWriter) write(dbCompany *db.Company) error {
+	return nw.encoder.Encode(newCompanyResponse(dbCompany))
+}
+
+func (nw *ndjsonCompaniesWriter) flush() error {
+	return nil
+}
@@ -0,0 +1,194 @@
+package webapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type GetCompaniesExportSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestGetCompaniesExportSuite(t *testing.T) {
+	s := new(GetCompaniesExportSuite)
+	suite.Run(t, s)
+}
+
+func (s *GetCompaniesExportSuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil).
+		Maybe()
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("export-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *GetCompaniesExportSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *GetCompaniesExportSuite) TestGetCompaniesExport_OK() {
+	t := s.T()
+
+	// create companies
+	companyIDs := []string{"0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", "0b1c2d3e-4f50-4a6b-8c7d-9e0f1a2b3c4d"}
+	createdAt := time.Date(2022, 9, 15, 15, 4, 17, 0, time.UTC)
+	fakeCreatedAtPatch := gomonkey.ApplyFunc(NewCreatedAt, func() time.Time {
+		return createdAt
+	})
+	defer fakeCreatedAtPatch.Reset()
+	fakeUpdatedAtPatch := gomonkey.ApplyFunc(NewUpdatedAt, func() time.Time {
+		return createdAt
+	})
+	defer fakeUpdatedAtPatch.Reset()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	for i, companyID := range companyIDs {
+		fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+			return uuid.MustParse(companyID)
+		})
+		body := fmt.Sprintf(`{"name": "ltd %d", "code": "EXPORT%d", "country": "CY", "type": "Corporation"}`, i, i)
+		response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies", strings.NewReader(body), metadata)
+		fakePatch.Reset()
+		assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+	}
+	tagsURL := fmt.Sprintf("/api/v1/companies/%s/tags", companyIDs[0])
+	response := makeTestRequest(s.router, http.MethodPost, tagsURL, strings.NewReader(`{"tags": ["vip", "eu"]}`), metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of attach tags must match")
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/export?format=csv&code_prefix=EXPORT",
+		nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+	assert.Equal(t, "text/csv; charset=utf-8", response.Header().Get("Content-Type"), "content type must match")
+
+	// assert HTTP body
+	expectedCSV := "id,name,code,country,website,phone,description,employees_count,registered,type," +
+		"created_at,updated_at,version,deleted_at,parent_id,tags,created_by,updated_by\n" +
+		"0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d,ltd 0,EXPORT0,CY,,,,0,false,Corporation," +
+		"2022-09-15T15:04:17Z,2022-09-15T15:04:17Z,2,,,\"eu,vip\",test/export-tester,test/export-tester\n" +
+		"0b1c2d3e-4f50-4a6b-8c7d-9e0f1a2b3c4d,ltd 1,EXPORT1,CY,,,,0,false,Corporation," +
+		"2022-09-15T15:04:17Z,2022-09-15T15:04:17Z,1,,,,test/export-tester,test/export-tester\n"
+	assert.Equal(t, expectedCSV, response.Body.String(), "csv must match")
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/export?format=ndjson&code_prefix=EXPORT",
+		nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	lines := bytes.Split(bytes.TrimSuffix(response.Body.Bytes(), []byte("\n")), []byte("\n"))
+	if !assert.Len(t, lines, len(companyIDs), "lines count must match") {
+		return
+	}
+	for i, line := range lines {
+		gotCompany := new(CompanyResponse)
+		if err := json.Unmarshal(line, gotCompany); err != nil {
+			t.Fatalf("decode line failed: %s", err)
+		}
+		assert.Equal(t, companyIDs[i], gotCompany.ID.String(), "company id must match")
+	}
+}
+
+func (s *GetCompaniesExportSuite) TestGetCompaniesExport_Empty() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/export?format=csv&country=ZZ", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	expectedCSV := "id,name,code,country,website,phone,description,employees_count,registered,type," +
+		"created_at,updated_at,version,deleted_at,parent_id,tags,created_by,updated_by\n"
+	assert.Equal(t, expectedCSV, response.Body.String(), "csv must contain only the header")
+}
+
+func (s *GetCompaniesExportSuite) TestGetCompaniesExport_InvalidFormat() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/export?format=xml", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusBadRequest, response.Code, "http code must match")
+
+	// assert HTTP body
+	assert.JSONEq(t, `{"error": "format must be csv or ndjson"}`, response.Body.String(), "http body must match")
+}
+
+func TestCompaniesExportColumns(t *testing.T) {
+	parentID := uuid.New()
+	deletedAt := time.Now()
+	company := CompanyResponse{DeletedAt: &deletedAt, ParentID: &parentID}
+	encoded, err := json.Marshal(company)
+	if err != nil {
+		t.Fatalf("encode company failed: %s", err)
+	}
+	fields := make(map[string]any)
+	if err = json.Unmarshal(encoded, &fields); err != nil {
+		t.Fatalf("decode company failed: %s", err)
+	}
+	expectedColumns := make([]string, 0, len(fields))
+	for field := range fields {
+		expectedColumns = append(expectedColumns, field)
+	}
+	assert.ElementsMatch(t, expectedColumns, companiesExportColumns, "csv columns must match fields of company")
+}
@@ -0,0 +1,231 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+)
+
+type GetCompaniesSuite struct {
+	dbSuite
+	router   *chi.Mux
+	adminJWT string
+}
+
+func TestGetCompaniesSuite(t *testing.T) {
+	s := new(GetCompaniesSuite)
+	suite.Run(t, s)
+}
+
+func (s *GetCompaniesSuite) SetupSuite() {
+	s.dbSuite.SetupSuite()
+	s.loadFixtures("fixtures/companies.yaml")
+}
+
+func (s *GetCompaniesSuite) SetupTest() {
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	adminJWT, err := tokenService.IssueToken(authn.AsAdmin())
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:       s.logger,
+		Handler:      handler,
+		GeoIPConf:    s.appConf.GeoIP,
+		TokenService: tokenService,
+	}
+	router := CreateRouter(routerParams)
+	s.router = router
+	s.adminJWT = adminJWT
+}
+
+func (s *GetCompaniesSuite) TearDownTest() {}
+
+func (s *GetCompaniesSuite) TestGetCompanies_Pagination() {
+	t := s.T()
+
+	// make request of the first page
+	testURL := "/api/v1/companies?country=cy&sort=name&limit=1"
+	response := makeTestRequest(s.router, http.MethodGet, testURL, nil, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	firstPage := new(struct {
+		Data       []CompanyResponse `json:"data"`
+		NextCursor string            `json:"next_cursor"`
+	})
+	if err = json.Unmarshal(gotBody, firstPage); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if assert.Len(t, firstPage.Data, 1, "first page size must match") {
+		assert.Equal(t, "TestPatchCompany_OK", firstPage.Data[0].Name, "first company must match")
+	}
+	assert.NotEmpty(t, firstPage.NextCursor, "next cursor must be returned")
+
+	// make request of the second page
+	testURL = fmt.Sprintf("/api/v1/companies?country=cy&sort=name&limit=1&cursor=%s", firstPage.NextCursor)
+	response = makeTestRequest(s.router, http.MethodGet, testURL, nil, nil)
+
+	// assert HTTP code
+	gotHTTPCode = response.Code
+	gotBody, err = io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"data": [
+		{
+			"id": "9d1c33ff-5a31-4c35-8d3a-4f5bb8b1c0a2",
+			"name": "TestPatchCompany_PreconditionFailed",
+			"code": "PRECOND",
+			"country": "CY",
+			"website": "https://mars.red",
+			"phone": "+11223345",
+			"description": "",
+			"employees_count": 0,
+			"registered": false,
+			"type": "Corporation",
+			"created_at": "2022-09-17T10:00:00Z",
+			"updated_at": "2022-09-17T10:00:00Z",
+			"version": 1,
+			"tags": [],
+			"created_by": "",
+			"updated_by": ""
+		}
+	]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *GetCompaniesSuite) TestGetCompanies_Filter() {
+	t := s.T()
+
+	// make request
+	testURL := "/api/v1/companies?code_prefix=O&name=getcompany&created_from=2022-09-16T16:00:00Z&created_to=2022-09-16T17:00:00Z"
+	response := makeTestRequest(s.router, http.MethodGet, testURL, nil, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"data": [
+		{
+			"id": "43fa9b5e-87bf-45d1-ad3a-b15df0037f37",
+			"name": "TestGetCompany_OK",
+			"code": "OK",
+			"country": "Moon",
+			"website": "Moon.dark",
+			"phone": "+65748329",
+			"description": "",
+			"employees_count": 0,
+			"registered": false,
+			"type": "Corporation",
+			"created_at": "2022-09-16T16:05:15Z",
+			"updated_at": "2022-09-16T16:05:15Z",
+			"version": 1,
+			"tags": [],
+			"created_by": "",
+			"updated_by": ""
+		}
+	]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *GetCompaniesSuite) TestGetCompanies_InvalidSort() {
+	t := s.T()
+
+	// make request
+	testURL := "/api/v1/companies?sort=phone"
+	response := makeTestRequest(s.router, http.MethodGet, testURL, nil, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusBadRequest
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "invalid sort: \"phone\""
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *GetCompaniesSuite) TestGetCompanies_IncludeDeletedByAdmin() {
+	t := s.T()
+
+	// make request
+	metadata := &testRequestMetaData{
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.adminJWT),
+		},
+	}
+	testURL := "/api/v1/companies?country=CY&code_prefix=DEL&include_deleted=true"
+	response := makeTestRequest(s.router, http.MethodGet, testURL, nil, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"data": [
+		{
+			"id": "43fa9b5e-87bf-45d1-ad3a-b15df0037f38",
+			"name": "TestGetCompany_Deleted",
+			"code": "DELETED",
+			"country": "CY",
+			"website": "https://deleted.cy",
+			"phone": "+35722000000",
+			"description": "",
+			"employees_count": 0,
+			"registered": false,
+			"type": "Corporation",
+			"created_at": "2022-09-16T16:05:15Z",
+			"updated_at": "2022-09-17T16:05:15Z",
+			"deleted_at": "2022-09-17T16:05:15Z",
+			"version": 2,
+			"tags": [],
+			"created_by": "",
+			"updated_by": ""
+		}
+	]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
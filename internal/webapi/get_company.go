@@ -1,19 +1,32 @@
 package webapi
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/pzabolotniy/logging/pkg/logging"
 
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
 )
 
+// Values of comma separated include parameter embedding sub-resources into the company.
+const (
+	IncludeContacts  = "contacts"
+	IncludeAddresses = "addresses"
+)
+
 type GetCompanyResponse struct {
 	CompanyResponse
+	// Contacts are returned only with include=contacts, pointer keeps empty list in the response.
+	Contacts *[]ContactResponse `json:"contacts,omitempty"`
+	// Addresses are returned only with include=addresses.
+	Addresses *[]AddressResponse `json:"addresses,omitempty"`
 }
 
 func (h *HandlerEnv) GetCompany(w http.ResponseWriter, r *http.Request) {
@@ -30,7 +43,33 @@ func (h *HandlerEnv) GetCompany(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbCompany, err := db.GetCompanyByID(ctx, dbConn, companyID)
+	withDeleted := r.URL.Query().Get("include_deleted") == "true"
+	if !canSeeDeleted(ctx, w, withDeleted) {
+		return
+	}
+	withContacts, withAddresses := false, false
+	if rawInclude := r.URL.Query().Get("include"); rawInclude != "" {
+		for _, include := range strings.Split(rawInclude, ",") {
+			switch strings.TrimSpace(include) {
+			case IncludeContacts:
+				withContacts = true
+			case IncludeAddresses:
+				withAddresses = true
+			default:
+				logger.WithField("include", rawInclude).Warn("invalid include")
+				BadRequest(ctx, w, "include must be list of "+IncludeContacts+", "+IncludeAddresses)
+
+				return
+			}
+		}
+	}
+
+	var dbCompany *db.Company
+	if withDeleted {
+		dbCompany, err = db.GetCompanyByIDWithDeleted(ctx, dbConn, companyID)
+	} else {
+		dbCompany, err = db.GetCompanyByID(ctx, dbConn, companyID)
+	}
 	if err != nil {
 		logger.WithError(err).WithField("company_id", companyID).Warn("get company failed")
 		if errors.Is(err, sql.ErrNoRows) {
@@ -44,17 +83,42 @@ func (h *HandlerEnv) GetCompany(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := &GetCompanyResponse{
-		CompanyResponse: CompanyResponse{
-			CreatedAt: dbCompany.CreatedAt,
-			InputCompany: InputCompany{
-				Name:    dbCompany.Name,
-				Code:    dbCompany.Code,
-				Country: dbCompany.Country,
-				WebSite: dbCompany.WebSite,
-				Phone:   dbCompany.Phone,
-			},
-			ID: dbCompany.ID,
-		},
+		CompanyResponse: newCompanyResponse(dbCompany),
+	}
+	if withContacts {
+		contacts, listErr := db.ListCompanyContacts(ctx, dbConn, companyID)
+		if listErr != nil {
+			logger.WithError(listErr).WithField("company_id", companyID).Error("list company contacts failed")
+			InternalServerError(ctx, w, "get company failed")
+
+			return
+		}
+		contactsResponse := newContactsResponse(contacts)
+		response.Contacts = &contactsResponse
+	}
+	if withAddresses {
+		addresses, listErr := db.ListCompanyAddresses(ctx, dbConn, companyID)
+		if listErr != nil {
+			logger.WithError(listErr).WithField("company_id", companyID).Error("list company addresses failed")
+			InternalServerError(ctx, w, "get company failed")
+
+			return
+		}
+		addressesResponse := newAddressesResponse(addresses)
+		response.Addresses = &addressesResponse
 	}
+	w.Header().Set("ETag", companyETag(dbCompany.Version))
 	OKResponse(ctx, w, response)
 }
+
+// canSeeDeleted responds with 403 and returns false when not admin client asks for deleted companies.
+func canSeeDeleted(ctx context.Context, w http.ResponseWriter, withDeleted bool) bool {
+	if !withDeleted || authn.IsAdmin(ctx) {
+		return true
+	}
+	logger := logging.FromContext(ctx)
+	logger.Warn("deleted companies requested by not admin")
+	Forbidden(ctx, w, "deleted companies are available to admins only")
+
+	return false
+}
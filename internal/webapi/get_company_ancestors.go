@@ -0,0 +1,34 @@
+package webapi
+
+import (
+	"net/http"
+
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// GetCompanyAncestors returns the chain of parents from the direct parent to the top level company of the group.
+func (h *HandlerEnv) GetCompanyAncestors(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	dbCompany, ok := getHierarchyCompany(ctx, w, r, dbConn)
+	if !ok {
+		return
+	}
+	dbCompanies, err := db.ListCompanyAncestors(ctx, dbConn, dbCompany.ID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", dbCompany.ID).Error("list company ancestors failed")
+		InternalServerError(ctx, w, "list ancestors failed")
+
+		return
+	}
+
+	response := make(CompaniesListResponse, 0, len(dbCompanies))
+	for i := range dbCompanies {
+		response = append(response, newCompanyResponse(&dbCompanies[i]))
+	}
+	OKResponse(ctx, w, response)
+}
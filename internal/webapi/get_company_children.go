@@ -0,0 +1,34 @@
+package webapi
+
+import (
+	"net/http"
+
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// GetCompanyChildren returns direct subsidiaries of the company ordered by name.
+func (h *HandlerEnv) GetCompanyChildren(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	dbCompany, ok := getHierarchyCompany(ctx, w, r, dbConn)
+	if !ok {
+		return
+	}
+	dbCompanies, err := db.ListCompanyChildren(ctx, dbConn, dbCompany.ID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", dbCompany.ID).Error("list company children failed")
+		InternalServerError(ctx, w, "list children failed")
+
+		return
+	}
+
+	response := make(CompaniesListResponse, 0, len(dbCompanies))
+	for i := range dbCompanies {
+		response = append(response, newCompanyResponse(&dbCompanies[i]))
+	}
+	OKResponse(ctx, w, response)
+}
@@ -0,0 +1,52 @@
+package webapi
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+type CompanyContactsResponse []ContactResponse
+
+// GetCompanyContacts returns all contacts of the not deleted company.
+func (h *HandlerEnv) GetCompanyContacts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return
+	}
+
+	if _, err = db.GetCompanyByID(ctx, dbConn, companyID); err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Warn("get company failed")
+		if errors.Is(err, sql.ErrNoRows) {
+			NotFound(ctx, w, "company not found")
+
+			return
+		}
+		InternalServerError(ctx, w, "get company failed")
+
+		return
+	}
+	contacts, err := db.ListCompanyContacts(ctx, dbConn, companyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("list company contacts failed")
+		InternalServerError(ctx, w, "list contacts failed")
+
+		return
+	}
+
+	OKResponse(ctx, w, CompanyContactsResponse(newContactsResponse(contacts)))
+}
@@ -0,0 +1,155 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/outbox"
+)
+
+// LastEventIDHeader is sent by SSE clients on reconnect with ID of the last received event.
+const LastEventIDHeader = "Last-Event-ID"
+
+const (
+	DefaultEventsPollInterval      = time.Second
+	DefaultEventsHeartbeatInterval = 15 * time.Second
+	DefaultEventsBatchSize         = 100
+)
+
+// GetCompanyEvents streams company events as Server-Sent Events until the client disconnects.
+// Every event has id of the outbox event, type as the event name and JSON of the event as data.
+// Stream starts after the event from Last-Event-ID header, without it only new events are sent.
+func (h *HandlerEnv) GetCompanyEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+	eventsConf := eventsConfWithDefaults(h.EventsConf)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.Error("response writer does not support flushing")
+		InternalServerError(ctx, w, "streaming is not supported")
+
+		return
+	}
+
+	var lastID int64
+	if rawLastID := r.Header.Get(LastEventIDHeader); rawLastID != "" {
+		var err error
+		lastID, err = strconv.ParseInt(rawLastID, 10, 64)
+		if err != nil || lastID < 0 {
+			logger.WithField("last_event_id", rawLastID).Warn("parse last event id failed")
+			BadRequest(ctx, w, "invalid "+LastEventIDHeader)
+
+			return
+		}
+	} else {
+		var err error
+		lastID, err = db.GetLastCompanyEventID(ctx, dbConn)
+		if err != nil {
+			logger.WithError(err).Error("get last company event id failed")
+			InternalServerError(ctx, w, "get company events failed")
+
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disables response buffering of nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	pollTicker := time.NewTicker(eventsConf.PollInterval)
+	defer pollTicker.Stop()
+	heartbeatTicker := time.NewTicker(eventsConf.HeartbeatInterval)
+	defer heartbeatTicker.Stop()
+	for {
+		events, err := db.ListCompanyEvents(ctx, dbConn, lastID, eventsConf.BatchSize)
+		if err != nil {
+			// the client reconnects and resumes from the last received event
+			logger.WithError(err).WithField("last_event_id", lastID).Error("list company events failed")
+
+			return
+		}
+		for i := range events {
+			if err = writeCompanyEvent(w, &events[i]); err != nil {
+				logger.WithError(err).WithField("event_id", events[i].ID).Warn("write company event failed")
+
+				return
+			}
+			lastID = events[i].ID
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+			heartbeatTicker.Reset(eventsConf.HeartbeatInterval)
+		}
+		// full batch means there can be more events
+		if len(events) == eventsConf.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-pollTicker.C:
+		case <-heartbeatTicker.C:
+			if _, err = io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				logger.WithError(err).Warn("write heartbeat failed")
+
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeCompanyEvent writes the event in SSE format, data is the same JSON as the one published from the outbox.
+func writeCompanyEvent(w io.Writer, dbEvent *db.CompanyEvent) error {
+	data, err := json.Marshal(outbox.Event{
+		ID:        dbEvent.ID,
+		Type:      dbEvent.Type,
+		CompanyID: dbEvent.CompanyID,
+		Payload:   dbEvent.Payload,
+		CreatedAt: dbEvent.CreatedAt,
+		TenantID:  dbEvent.TenantID,
+	})
+	if err != nil {
+		return fmt.Errorf("encode event failed: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", dbEvent.ID, dbEvent.Type, data)
+
+	return err
+}
+
+func eventsConfWithDefaults(eventsConf *config.Events) config.Events {
+	withDefaults := config.Events{
+		PollInterval:      DefaultEventsPollInterval,
+		HeartbeatInterval: DefaultEventsHeartbeatInterval,
+		BatchSize:         DefaultEventsBatchSize,
+	}
+	if eventsConf == nil {
+		return withDefaults
+	}
+	if eventsConf.PollInterval > 0 {
+		withDefaults.PollInterval = eventsConf.PollInterval
+	}
+	if eventsConf.HeartbeatInterval > 0 {
+		withDefaults.HeartbeatInterval = eventsConf.HeartbeatInterval
+	}
+	if eventsConf.BatchSize > 0 {
+		withDefaults.BatchSize = eventsConf.BatchSize
+	}
+
+	return withDefaults
+}
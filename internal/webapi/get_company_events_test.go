@@ -0,0 +1,148 @@
+package webapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type GetCompanyEventsSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestGetCompanyEventsSuite(t *testing.T) {
+	s := new(GetCompanyEventsSuite)
+	suite.Run(t, s)
+}
+
+func (s *GetCompanyEventsSuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil).
+		Maybe()
+
+	handler := &HandlerEnv{
+		DbConn:     s.dbConn,
+		EventsConf: &config.Events{PollInterval: 10 * time.Millisecond},
+	}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("events-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *GetCompanyEventsSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *GetCompanyEventsSuite) TestGetCompanyEvents_Resume() {
+	t := s.T()
+
+	// create the company
+	companyID := "5e2f3a40-7b8c-4d9e-a0f1-b2c3d4e5f607"
+	fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+		return uuid.MustParse(companyID)
+	})
+	defer fakePatch.Reset()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "EVENTS", "country": "CY", "type": "Corporation"}`), metadata)
+	assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+
+	// make request, the stream is closed by the client
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/api/v1/companies/events", nil)
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.testJWT))
+	request.Header.Set(LastEventIDHeader, "0")
+	request.RemoteAddr = metadata.remoteAddr
+	request.RequestURI = "/api/v1/companies/events"
+	streamResponse := httptest.NewRecorder()
+	s.router.ServeHTTP(streamResponse, request)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, streamResponse.Code, "http code must match")
+	assert.Equal(t, "text/event-stream", streamResponse.Header().Get("Content-Type"), "content type must match")
+
+	// assert the stream
+	gotStream := streamResponse.Body.String()
+	assert.True(t, strings.HasPrefix(gotStream, "id: 1\nevent: CompanyCreated\ndata: "), "first event must match")
+	assert.Contains(t, gotStream, `"company_id":"`+companyID+`"`, "company id must match")
+}
+
+func (s *GetCompanyEventsSuite) TestGetCompanyEvents_InvalidLastEventID() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization":   fmt.Sprintf("Bearer %s", s.testJWT),
+			LastEventIDHeader: "last",
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/events", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusBadRequest, response.Code, "http code must match")
+}
+
+func TestWriteCompanyEvent(t *testing.T) {
+	event := &db.CompanyEvent{
+		ID:        12,
+		Type:      db.CompanyDeleted,
+		CompanyID: uuid.MustParse("3997db3d-f747-4f00-adf8-1d2c71d2a911"),
+		Payload:   []byte(`{"name":"ltd"}`),
+		CreatedAt: time.Date(2022, 9, 15, 15, 4, 17, 0, time.UTC),
+		TenantID:  "acme",
+	}
+	buffer := new(bytes.Buffer)
+
+	if err := writeCompanyEvent(buffer, event); err != nil {
+		t.Fatalf("write event failed: %s", err)
+	}
+
+	expectedStream := "id: 12\nevent: CompanyDeleted\n" +
+		`data: {"id":12,"type":"CompanyDeleted","company_id":"3997db3d-f747-4f00-adf8-1d2c71d2a911",` +
+		`"payload":{"name":"ltd"},"created_at":"2022-09-15T15:04:17Z","tenant_id":"acme"}` + "\n\n"
+	assert.Equal(t, expectedStream, buffer.String(), "stream must match")
+}
@@ -0,0 +1,197 @@
+package webapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// historyIgnoredFields change on every write, so they are not reported in diffs,
+// updated_by is the actor of the record.
+var historyIgnoredFields = map[string]bool{
+	"updated_at": true,
+	"updated_by": true,
+	"version":    true,
+}
+
+type CompanyFieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+type CompanyHistoryRecord struct {
+	ID        int64                 `json:"id"`
+	Action    db.CompanyAuditAction `json:"action"`
+	Actor     string                `json:"actor"`
+	RequestID *uuid.UUID            `json:"request_id,omitempty"`
+	CreatedAt time.Time             `json:"created_at"`
+	// Before is omitted on create.
+	Before  json.RawMessage      `json:"before,omitempty"`
+	After   json.RawMessage      `json:"after"`
+	Changes []CompanyFieldChange `json:"changes"`
+}
+
+type CompanyHistoryResponse []CompanyHistoryRecord
+
+// GetCompanyHistory returns the page of changes of the company from the newest one,
+// use next_cursor of the response to get the next page.
+// History of deleted and purged companies is kept.
+func (h *HandlerEnv) GetCompanyHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return
+	}
+	limit, beforeID, err := parseIDPageQuery(r.URL.Query())
+	if err != nil {
+		logger.WithError(err).Warn("parse query failed")
+		BadRequest(ctx, w, err.Error())
+
+		return
+	}
+
+	// one more record is requested to know whether the next page exists
+	records, err := db.ListCompanyAudit(ctx, dbConn, companyID, beforeID, limit+1)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("list company history failed")
+		InternalServerError(ctx, w, "list company history failed")
+
+		return
+	}
+	nextCursor := ""
+	if len(records) > limit {
+		records = records[:limit]
+		nextCursor = encodeIDCursor(records[limit-1].ID)
+	}
+
+	response := make(CompanyHistoryResponse, 0, len(records))
+	for i := range records {
+		historyRecord, recordErr := newCompanyHistoryRecord(&records[i])
+		if recordErr != nil {
+			logger.WithError(recordErr).WithField("audit_id", records[i].ID).Error("decode company history failed")
+			InternalServerError(ctx, w, "list company history failed")
+
+			return
+		}
+		response = append(response, historyRecord)
+	}
+	OKPageResponse(ctx, w, response, nextCursor)
+}
+
+// parseIDPageQuery parses limit and cursor of the page ordered by numeric ID from the newest one.
+func parseIDPageQuery(query url.Values) (int, int64, error) {
+	limit := DefaultCompaniesPageSize
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(rawLimit)
+		if err != nil || limit < 1 || limit > MaxCompaniesPageSize {
+			return 0, 0, fmt.Errorf("limit must be from 1 to %d", MaxCompaniesPageSize)
+		}
+	}
+	var beforeID int64
+	if rawCursor := query.Get("cursor"); rawCursor != "" {
+		var err error
+		beforeID, err = decodeIDCursor(rawCursor)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid cursor: %w", err)
+		}
+	}
+
+	return limit, beforeID, nil
+}
+
+// encodeIDCursor points to the last record of the page ordered by numeric ID.
+func encodeIDCursor(lastID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(lastID, 10)))
+}
+
+func decodeIDCursor(rawCursor string) (int64, error) {
+	encodedCursor, err := base64.RawURLEncoding.DecodeString(rawCursor)
+	if err != nil {
+		return 0, fmt.Errorf("decode base64 failed: %w", err)
+	}
+	lastID, err := strconv.ParseInt(string(encodedCursor), 10, 64)
+	if err != nil || lastID < 1 {
+		return 0, fmt.Errorf("decode id failed: %q", encodedCursor)
+	}
+
+	return lastID, nil
+}
+
+func newCompanyHistoryRecord(record *db.CompanyAuditRecord) (CompanyHistoryRecord, error) {
+	changes, err := companyFieldChanges(record.Before, record.After)
+	if err != nil {
+		return CompanyHistoryRecord{}, err
+	}
+
+	return CompanyHistoryRecord{
+		ID:        record.ID,
+		Action:    record.Action,
+		Actor:     record.Actor,
+		RequestID: record.RequestID,
+		CreatedAt: record.CreatedAt,
+		Before:    record.Before,
+		After:     record.After,
+		Changes:   changes,
+	}, nil
+}
+
+// companyFieldChanges compares fields of the company JSON before and after the change,
+// changed fields are returned sorted by name.
+func companyFieldChanges(before, after []byte) ([]CompanyFieldChange, error) {
+	beforeFields := make(map[string]any)
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &beforeFields); err != nil {
+			return nil, fmt.Errorf("decode company before change failed: %w", err)
+		}
+	}
+	afterFields := make(map[string]any)
+	if err := json.Unmarshal(after, &afterFields); err != nil {
+		return nil, fmt.Errorf("decode company after change failed: %w", err)
+	}
+
+	names := make([]string, 0, len(afterFields))
+	for name := range afterFields {
+		names = append(names, name)
+	}
+	for name := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]CompanyFieldChange, 0)
+	for _, name := range names {
+		if historyIgnoredFields[name] || reflect.DeepEqual(beforeFields[name], afterFields[name]) {
+			continue
+		}
+		changes = append(changes, CompanyFieldChange{
+			Field: name,
+			Old:   beforeFields[name],
+			New:   afterFields[name],
+		})
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,150 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type GetCompanyHistorySuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestGetCompanyHistorySuite(t *testing.T) {
+	s := new(GetCompanyHistorySuite)
+	suite.Run(t, s)
+}
+
+func (s *GetCompanyHistorySuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil)
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("history-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *GetCompanyHistorySuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *GetCompanyHistorySuite) TestGetCompanyHistory_OK() {
+	t := s.T()
+
+	// create and update the company
+	companyID := "7c3e5a10-2b4d-4f6e-8a9c-0b1d2e3f4a50"
+	fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+		return uuid.MustParse(companyID)
+	})
+	defer fakePatch.Reset()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "HISTORY", "country": "CY", "type": "Corporation"}`), metadata)
+	assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+	metadata.headers["Content-Type"] = "application/merge-patch+json"
+	response = makeTestRequest(s.router, http.MethodPatch, "/api/v1/companies/"+companyID,
+		strings.NewReader(`{"name": "new ltd", "employees_count": 10}`), metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of update must match")
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/companies/"+companyID+"/history?limit=1", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data       CompanyHistoryResponse `json:"data"`
+		NextCursor string                 `json:"next_cursor"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if !assert.Len(t, gotBody.Data, 1, "records count must match") {
+		return
+	}
+	updateRecord := gotBody.Data[0]
+	assert.Equal(t, db.CompanyAuditUpdate, updateRecord.Action, "action must match")
+	assert.Equal(t, "test/history-tester", updateRecord.Actor, "actor must match")
+	assert.NotNil(t, updateRecord.RequestID, "request id must be recorded")
+	expectedChanges := []CompanyFieldChange{
+		{Field: "employees_count", Old: float64(0), New: float64(10)},
+		{Field: "name", Old: "ltd", New: "new ltd"},
+	}
+	assert.Equal(t, expectedChanges, updateRecord.Changes, "changes must match")
+	assert.NotEmpty(t, gotBody.NextCursor, "next cursor must be returned")
+
+	// get the next page
+	response = makeTestRequest(s.router, http.MethodGet,
+		"/api/v1/companies/"+companyID+"/history?limit=1&cursor="+gotBody.NextCursor, nil, metadata)
+	assert.Equal(t, http.StatusOK, response.Code, "http code of the next page must match")
+	gotBody = new(struct {
+		Data       CompanyHistoryResponse `json:"data"`
+		NextCursor string                 `json:"next_cursor"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if !assert.Len(t, gotBody.Data, 1, "records count of the next page must match") {
+		return
+	}
+	assert.Equal(t, db.CompanyAuditCreate, gotBody.Data[0].Action, "action must match")
+	assert.Empty(t, gotBody.Data[0].Before, "before must be omitted on create")
+	assert.Empty(t, gotBody.NextCursor, "next cursor must be empty on the last page")
+}
+
+func TestCompanyFieldChanges(t *testing.T) {
+	before := []byte(`{"name": "ltd", "code": "007", "version": 1, "updated_at": "2022-09-15T15:04:17Z",` +
+		` "updated_by": "creator"}`)
+	after := []byte(`{"name": "ltd", "code": "008", "version": 2, "updated_at": "2022-09-16T15:04:17Z",` +
+		` "updated_by": "editor", "deleted_at": "2022-09-16T15:04:17Z"}`)
+
+	changes, err := companyFieldChanges(before, after)
+	if err != nil {
+		t.Fatalf("compare fields failed: %s", err)
+	}
+
+	expectedChanges := []CompanyFieldChange{
+		{Field: "code", Old: "007", New: "008"},
+		{Field: "deleted_at", Old: nil, New: "2022-09-16T15:04:17Z"},
+	}
+	assert.Equal(t, expectedChanges, changes, "changes must match")
+}
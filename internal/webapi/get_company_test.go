@@ -16,6 +16,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/migration"
@@ -29,6 +30,7 @@ type GetCompanySuite struct {
 	logger     logging.Logger
 	appConf    *config.App
 	router     *chi.Mux
+	adminJWT   string
 }
 
 func TestGetCompanySuite(t *testing.T) {
@@ -108,13 +110,20 @@ func (s *GetCompanySuite) TearDownSuite() {
 
 func (s *GetCompanySuite) SetupTest() {
 	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	adminJWT, err := tokenService.IssueToken(authn.AsAdmin())
+	if err != nil {
+		s.T().Fatal(err)
+	}
 	routerParams := &RouterParams{
-		Logger:    s.logger,
-		Handler:   handler,
-		GeoIPConf: s.appConf.GeoIP,
+		Logger:       s.logger,
+		Handler:      handler,
+		GeoIPConf:    s.appConf.GeoIP,
+		TokenService: tokenService,
 	}
 	router := CreateRouter(routerParams)
 	s.router = router
+	s.adminJWT = adminJWT
 }
 
 func (s *GetCompanySuite) TearDownTest() {}
@@ -137,6 +146,7 @@ func (s *GetCompanySuite) TestGetCompany_OK() {
 	}
 	expectedHTTPCode := http.StatusOK
 	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+	assert.Equal(t, `"1"`, response.Header().Get("ETag"), "etag must match")
 
 	// assert HTTP body
 	expectedHTTPBody := `{
@@ -147,7 +157,16 @@ func (s *GetCompanySuite) TestGetCompany_OK() {
 		"country": "Moon",
 		"website": "Moon.dark",
 		"phone": "+65748329",
-		"created_at": "2022-09-16T16:05:15Z"
+		"description": "",
+		"employees_count": 0,
+		"registered": false,
+		"type": "Corporation",
+		"created_at": "2022-09-16T16:05:15Z",
+		"updated_at": "2022-09-16T16:05:15Z",
+		"version": 1,
+		"tags": [],
+		"created_by": "",
+		"updated_by": ""
 	}
 }`
 	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
@@ -178,3 +197,104 @@ func (s *GetCompanySuite) TestGetCompany_NotFound() {
 }`
 	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
 }
+
+func (s *GetCompanySuite) TestGetCompany_Deleted() {
+	t := s.T()
+
+	// testdata
+	companyID := "43fa9b5e-87bf-45d1-ad3a-b15df0037f38"
+
+	// make request
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodGet, testURL, nil, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusNotFound
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "company not found"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *GetCompanySuite) TestGetCompany_IncludeDeletedByAdmin() {
+	t := s.T()
+
+	// testdata
+	companyID := "43fa9b5e-87bf-45d1-ad3a-b15df0037f38"
+
+	// make request
+	metadata := &testRequestMetaData{
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.adminJWT),
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s?include_deleted=true", companyID)
+	response := makeTestRequest(s.router, http.MethodGet, testURL, nil, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"data": {
+		"id": "43fa9b5e-87bf-45d1-ad3a-b15df0037f38",
+		"name": "TestGetCompany_Deleted",
+		"code": "DELETED",
+		"country": "CY",
+		"website": "https://deleted.cy",
+		"phone": "+35722000000",
+		"description": "",
+		"employees_count": 0,
+		"registered": false,
+		"type": "Corporation",
+		"created_at": "2022-09-16T16:05:15Z",
+		"updated_at": "2022-09-17T16:05:15Z",
+		"deleted_at": "2022-09-17T16:05:15Z",
+		"version": 2,
+		"tags": [],
+		"created_by": "",
+		"updated_by": ""
+	}
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *GetCompanySuite) TestGetCompany_IncludeDeletedForbidden() {
+	t := s.T()
+
+	// testdata
+	companyID := "43fa9b5e-87bf-45d1-ad3a-b15df0037f38"
+
+	// make request
+	testURL := fmt.Sprintf("/api/v1/companies/%s?include_deleted=true", companyID)
+	response := makeTestRequest(s.router, http.MethodGet, testURL, nil, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusForbidden
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "deleted companies are available to admins only"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
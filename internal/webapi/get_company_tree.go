@@ -0,0 +1,43 @@
+package webapi
+
+import (
+	"net/http"
+
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// GetCompanyTree returns the whole group of the company as the tree from its top level company.
+func (h *HandlerEnv) GetCompanyTree(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	dbCompany, ok := getHierarchyCompany(ctx, w, r, dbConn)
+	if !ok {
+		return
+	}
+	rootID := dbCompany.ID
+	if dbCompany.ParentID != nil {
+		ancestors, err := db.ListCompanyAncestors(ctx, dbConn, dbCompany.ID)
+		if err != nil {
+			logger.WithError(err).WithField("company_id", dbCompany.ID).Error("list company ancestors failed")
+			InternalServerError(ctx, w, "get tree failed")
+
+			return
+		}
+		if len(ancestors) > 0 {
+			rootID = ancestors[len(ancestors)-1].ID
+		}
+	}
+	dbCompanies, err := db.ListCompanyDescendants(ctx, dbConn, rootID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", rootID).Error("list company descendants failed")
+		InternalServerError(ctx, w, "get tree failed")
+
+		return
+	}
+
+	OKResponse(ctx, w, newCompanyTree(dbCompanies))
+}
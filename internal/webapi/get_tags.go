@@ -0,0 +1,44 @@
+package webapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+type TagResponse struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	// CompaniesCount is the number of not deleted companies having the tag.
+	CompaniesCount int `json:"companies_count"`
+}
+
+type TagsResponse []TagResponse
+
+// GetTags returns all tags ordered by name.
+func (h *HandlerEnv) GetTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	dbTags, err := db.ListTags(ctx, dbConn)
+	if err != nil {
+		logger.WithError(err).Error("list tags failed")
+		InternalServerError(ctx, w, "list tags failed")
+
+		return
+	}
+
+	response := make(TagsResponse, 0, len(dbTags))
+	for i := range dbTags {
+		response = append(response, TagResponse{
+			Name:           dbTags[i].Name,
+			CreatedAt:      dbTags[i].CreatedAt,
+			CompaniesCount: dbTags[i].CompaniesCount,
+		})
+	}
+	OKResponse(ctx, w, response)
+}
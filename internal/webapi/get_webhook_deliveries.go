@@ -0,0 +1,154 @@
+package webapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+type WebhookDeliveryAttemptResponse struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	// StatusCode is omitted if there was no response.
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID          int64                            `json:"id"`
+	EventID     int64                            `json:"event_id"`
+	EventType   db.CompanyEventType              `json:"event_type"`
+	Payload     json.RawMessage                  `json:"payload"`
+	Status      db.WebhookDeliveryStatus         `json:"status"`
+	Attempts    []WebhookDeliveryAttemptResponse `json:"attempts"`
+	CreatedAt   time.Time                        `json:"created_at"`
+	DeliveredAt *time.Time                       `json:"delivered_at,omitempty"`
+	// NextAttemptAt is set only for pending deliveries.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+type WebhookDeliveriesResponse []WebhookDeliveryResponse
+
+// GetWebhookDeliveries returns the page of deliveries of the webhook from the newest one with all their attempts,
+// use next_cursor of the response to get the next page.
+// Only the client registered the webhook and admins can see them.
+func (h *HandlerEnv) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlWebhookID := chi.URLParam(r, "webhookID")
+	webhookID, err := uuid.Parse(urlWebhookID)
+	if err != nil {
+		logger.WithError(err).WithField("webhook_id", urlWebhookID).Warn("parse webhookID failed")
+		BadRequest(ctx, w, "invalid webhookID")
+
+		return
+	}
+	limit, beforeID, err := parseIDPageQuery(r.URL.Query())
+	if err != nil {
+		logger.WithError(err).Warn("parse query failed")
+		BadRequest(ctx, w, err.Error())
+
+		return
+	}
+
+	subscription, err := db.GetWebhookSubscription(ctx, dbConn, webhookID)
+	if err != nil {
+		logger.WithError(err).WithField("webhook_id", webhookID).Warn("get webhook failed")
+		if errors.Is(err, db.ErrWebhookNotFound) {
+			NotFound(ctx, w, "webhook not found")
+
+			return
+		}
+		InternalServerError(ctx, w, "get webhook failed")
+
+		return
+	}
+	// webhooks of other clients are not disclosed
+	if !isWebhookVisible(ctx, subscription) {
+		logger.WithField("webhook_id", webhookID).Warn("webhook of another client requested")
+		NotFound(ctx, w, "webhook not found")
+
+		return
+	}
+
+	// one more delivery is requested to know whether the next page exists
+	deliveries, err := db.ListWebhookDeliveries(ctx, dbConn, webhookID, beforeID, limit+1)
+	if err != nil {
+		logger.WithError(err).WithField("webhook_id", webhookID).Error("list webhook deliveries failed")
+		InternalServerError(ctx, w, "list webhook deliveries failed")
+
+		return
+	}
+	nextCursor := ""
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		nextCursor = encodeIDCursor(deliveries[limit-1].ID)
+	}
+
+	deliveryIDs := make([]int64, 0, len(deliveries))
+	for i := range deliveries {
+		deliveryIDs = append(deliveryIDs, deliveries[i].ID)
+	}
+	attempts, err := db.ListWebhookDeliveryAttempts(ctx, dbConn, deliveryIDs)
+	if err != nil {
+		logger.WithError(err).WithField("webhook_id", webhookID).Error("list webhook delivery attempts failed")
+		InternalServerError(ctx, w, "list webhook deliveries failed")
+
+		return
+	}
+	deliveryAttempts := make(map[int64][]WebhookDeliveryAttemptResponse, len(deliveries))
+	for i := range attempts {
+		deliveryAttempts[attempts[i].DeliveryID] = append(
+			deliveryAttempts[attempts[i].DeliveryID],
+			newWebhookDeliveryAttemptResponse(&attempts[i]),
+		)
+	}
+
+	response := make(WebhookDeliveriesResponse, 0, len(deliveries))
+	for i := range deliveries {
+		response = append(response, newWebhookDeliveryResponse(&deliveries[i], deliveryAttempts[deliveries[i].ID]))
+	}
+	OKPageResponse(ctx, w, response, nextCursor)
+}
+
+func newWebhookDeliveryResponse(
+	delivery *db.WebhookDelivery, attempts []WebhookDeliveryAttemptResponse,
+) WebhookDeliveryResponse {
+	if attempts == nil {
+		attempts = make([]WebhookDeliveryAttemptResponse, 0)
+	}
+	response := WebhookDeliveryResponse{
+		ID:          delivery.ID,
+		EventID:     delivery.EventID,
+		EventType:   delivery.EventType,
+		Payload:     delivery.Payload,
+		Status:      delivery.Status,
+		Attempts:    attempts,
+		CreatedAt:   delivery.CreatedAt,
+		DeliveredAt: delivery.DeliveredAt,
+	}
+	if delivery.Status == db.WebhookDeliveryPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		response.NextAttemptAt = &nextAttemptAt
+	}
+
+	return response
+}
+
+func newWebhookDeliveryAttemptResponse(attempt *db.WebhookDeliveryAttempt) WebhookDeliveryAttemptResponse {
+	return WebhookDeliveryAttemptResponse{
+		AttemptedAt: attempt.AttemptedAt,
+		StatusCode:  attempt.StatusCode,
+		Error:       attempt.Error,
+		DurationMs:  attempt.DurationMs,
+	}
+}
@@ -0,0 +1,202 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/outbox"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/webhook"
+)
+
+type GetWebhookDeliveriesSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+	otherJWT            string
+}
+
+func TestGetWebhookDeliveriesSuite(t *testing.T) {
+	s := new(GetWebhookDeliveriesSuite)
+	suite.Run(t, s)
+}
+
+func (s *GetWebhookDeliveriesSuite) SetupTest() {
+	// webhooks are not restricted by country
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+
+	// the receiver of the test listens on loopback
+	handler := &HandlerEnv{DbConn: s.dbConn, WebhooksConf: &config.Webhooks{AllowPrivateNetworks: true}}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("webhooks-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	otherJWT, err := tokenService.IssueToken(authn.WithSubject("another-client"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.otherJWT = otherJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *GetWebhookDeliveriesSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *GetWebhookDeliveriesSuite) TestGetWebhookDeliveries_OK() {
+	t := s.T()
+	ctx := logging.WithContext(context.Background(), s.logger)
+
+	// the receiver fails the first attempt
+	secret := ""
+	receivedSignatures := make([]string, 0)
+	requestsCount := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.TimestampHeader), 10, 64)
+		receivedSignatures = append(receivedSignatures, r.Header.Get(webhook.SignatureHeader))
+		assert.Equal(t, webhook.Sign(secret, timestamp, body), r.Header.Get(webhook.SignatureHeader),
+			"signature must match")
+		requestsCount++
+		if requestsCount == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	webhookID := "1b7c2d3e-4f50-4a61-8b72-9c83d4e5f601"
+	fakePatch := gomonkey.ApplyFunc(NewWebhookID, func() uuid.UUID {
+		return uuid.MustParse(webhookID)
+	})
+	defer fakePatch.Reset()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/webhooks",
+		strings.NewReader(`{"url": "`+receiver.URL+`", "event_types": ["CompanyCreated"]}`), metadata)
+	assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+	createdBody := new(struct {
+		Data PostWebhookResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(createdBody); err != nil {
+		t.Fatalf("decode create response body failed: %s", err)
+	}
+	secret = createdBody.Data.Secret
+
+	// publish the event and make two attempts
+	event := &outbox.Event{
+		ID:        1,
+		Type:      db.CompanyCreated,
+		CompanyID: uuid.MustParse("3997db3d-f747-4f00-adf8-1d2c71d2a911"),
+		Payload:   json.RawMessage(`{"name":"ltd"}`),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := webhook.NewPublisher(s.dbConn).Publish(ctx, event); err != nil {
+		t.Fatalf("publish event failed: %s", err)
+	}
+	dispatcher := webhook.NewDispatcher(s.dbConn, &config.Webhooks{
+		BackoffBase:          time.Millisecond,
+		AllowPrivateNetworks: true,
+	})
+	for i := 0; i < 2; i++ {
+		time.Sleep(2 * time.Millisecond)
+		if _, err := dispatcher.DispatchBatch(ctx); err != nil {
+			t.Fatalf("dispatch deliveries failed: %s", err)
+		}
+	}
+	assert.Len(t, receivedSignatures, 2, "attempts count must match")
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/webhooks/"+webhookID+"/deliveries", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data       WebhookDeliveriesResponse `json:"data"`
+		NextCursor string                    `json:"next_cursor"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if !assert.Len(t, gotBody.Data, 1, "deliveries count must match") {
+		return
+	}
+	delivery := gotBody.Data[0]
+	assert.Equal(t, int64(1), delivery.EventID, "event id must match")
+	assert.Equal(t, db.CompanyCreated, delivery.EventType, "event type must match")
+	assert.Equal(t, db.WebhookDeliveryDelivered, delivery.Status, "status must match")
+	assert.NotNil(t, delivery.DeliveredAt, "delivered_at must be set")
+	assert.Nil(t, delivery.NextAttemptAt, "next_attempt_at must be omitted")
+	if !assert.Len(t, delivery.Attempts, 2, "attempts count must match") {
+		return
+	}
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.Attempts[0].StatusCode, "first status code must match")
+	assert.NotEmpty(t, delivery.Attempts[0].Error, "error of the first attempt must be set")
+	assert.Equal(t, http.StatusNoContent, delivery.Attempts[1].StatusCode, "second status code must match")
+	assert.Empty(t, delivery.Attempts[1].Error, "error of the second attempt must be empty")
+	assert.Empty(t, gotBody.NextCursor, "next cursor must be empty on the last page")
+}
+
+func (s *GetWebhookDeliveriesSuite) TestGetWebhookDeliveries_AnotherClient() {
+	t := s.T()
+	webhookID := "2c8d3e4f-5061-4b72-9c83-ad94e5f60712"
+	fakePatch := gomonkey.ApplyFunc(NewWebhookID, func() uuid.UUID {
+		return uuid.MustParse(webhookID)
+	})
+	defer fakePatch.Reset()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/webhooks",
+		strings.NewReader(`{"url": "https://example.com/hooks", "event_types": ["CompanyDeleted"]}`), metadata)
+	assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+
+	// make request
+	metadata.headers["Authorization"] = fmt.Sprintf("Bearer %s", s.otherJWT)
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/webhooks/"+webhookID+"/deliveries", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusNotFound, response.Code, "http code must match")
+}
@@ -0,0 +1,55 @@
+package webapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+type WebhooksResponse []WebhookResponse
+
+// GetWebhooks returns webhooks registered by the client in the order they were registered,
+// admins get all webhooks of the tenant. Secrets are not returned.
+func (h *HandlerEnv) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	response := make(WebhooksResponse, 0)
+	owner := authn.Principal(ctx)
+	if authn.IsAdmin(ctx) {
+		owner = ""
+	} else if owner == "" {
+		// clients without subject own no webhooks
+		OKResponse(ctx, w, response)
+
+		return
+	}
+	subscriptions, err := db.ListWebhookSubscriptions(ctx, dbConn, owner)
+	if err != nil {
+		logger.WithError(err).Error("list webhooks failed")
+		InternalServerError(ctx, w, "list webhooks failed")
+
+		return
+	}
+
+	for i := range subscriptions {
+		response = append(response, newWebhookResponse(&subscriptions[i]))
+	}
+	OKResponse(ctx, w, response)
+}
+
+// isWebhookVisible reports whether the client registered the webhook or is admin.
+// Clients without subject own no webhooks.
+func isWebhookVisible(ctx context.Context, subscription *db.WebhookSubscription) bool {
+	if authn.IsAdmin(ctx) {
+		return true
+	}
+	principal := authn.Principal(ctx)
+
+	return principal != "" && subscription.Owner == principal
+}
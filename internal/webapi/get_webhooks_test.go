@@ -0,0 +1,173 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type WebhooksSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+	otherJWT            string
+	adminJWT            string
+}
+
+func TestWebhooksSuite(t *testing.T) {
+	s := new(WebhooksSuite)
+	suite.Run(t, s)
+}
+
+func (s *WebhooksSuite) SetupTest() {
+	// webhooks are not restricted by country
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("webhooks-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	otherJWT, err := tokenService.IssueToken(authn.WithSubject("another-client"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	adminJWT, err := tokenService.IssueToken(authn.AsAdmin())
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.otherJWT = otherJWT
+	s.adminJWT = adminJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *WebhooksSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *WebhooksSuite) TestWebhooks_ListAndDelete() {
+	t := s.T()
+
+	// register webhooks of two clients
+	webhookIDs := []string{"4e0f5061-7283-4d94-be05-cf1607182934", "5f106172-8394-4ea5-8f16-d02718293a45"}
+	tokens := []string{s.testJWT, s.otherJWT}
+	for i, webhookID := range webhookIDs {
+		fakePatch := gomonkey.ApplyFunc(NewWebhookID, func() uuid.UUID {
+			return uuid.MustParse(webhookID)
+		})
+		metadata := &testRequestMetaData{
+			remoteAddr: "127.0.0.1:63099",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", tokens[i]),
+			},
+		}
+		body := fmt.Sprintf(`{"url": "https://example.com/hooks/%d", "event_types": ["CompanyCreated"]}`, i)
+		response := makeTestRequest(s.router, http.MethodPost, "/api/v1/webhooks", strings.NewReader(body), metadata)
+		fakePatch.Reset()
+		assert.Equal(t, http.StatusCreated, response.Code, "http code of create must match")
+	}
+
+	// clients see their own webhooks, admins see all of them
+	testCases := []struct {
+		name        string
+		token       string
+		expectedIDs []string
+	}{
+		{name: "owner", token: s.testJWT, expectedIDs: webhookIDs[:1]},
+		{name: "another client", token: s.otherJWT, expectedIDs: webhookIDs[1:]},
+		{name: "admin", token: s.adminJWT, expectedIDs: webhookIDs},
+	}
+	for _, tc := range testCases {
+		metadata := &testRequestMetaData{
+			remoteAddr: "127.0.0.1:63099",
+			headers: map[string]string{
+				"Authorization": fmt.Sprintf("Bearer %s", tc.token),
+			},
+		}
+		response := makeTestRequest(s.router, http.MethodGet, "/api/v1/webhooks", nil, metadata)
+		assert.Equal(t, http.StatusOK, response.Code, "http code of %s must match", tc.name)
+		gotBody := new(struct {
+			Data WebhooksResponse `json:"data"`
+		})
+		if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+			t.Fatalf("decode response body failed: %s", err)
+		}
+		gotIDs := make([]string, 0)
+		for _, webhook := range gotBody.Data {
+			gotIDs = append(gotIDs, webhook.ID.String())
+		}
+		assert.Equal(t, tc.expectedIDs, gotIDs, "webhooks of %s must match", tc.name)
+	}
+
+	// webhook of another client is not found
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.otherJWT),
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodDelete, "/api/v1/webhooks/"+webhookIDs[0], nil, metadata)
+	assert.Equal(t, http.StatusNotFound, response.Code, "http code of delete by another client must match")
+
+	// owner and admin delete webhooks
+	metadata.headers["Authorization"] = fmt.Sprintf("Bearer %s", s.testJWT)
+	response = makeTestRequest(s.router, http.MethodDelete, "/api/v1/webhooks/"+webhookIDs[0], nil, metadata)
+	assert.Equal(t, http.StatusNoContent, response.Code, "http code of delete by owner must match")
+	response = makeTestRequest(s.router, http.MethodDelete, "/api/v1/webhooks/"+webhookIDs[0], nil, metadata)
+	assert.Equal(t, http.StatusNotFound, response.Code, "http code of delete of deleted webhook must match")
+	metadata.headers["Authorization"] = fmt.Sprintf("Bearer %s", s.adminJWT)
+	response = makeTestRequest(s.router, http.MethodDelete, "/api/v1/webhooks/"+webhookIDs[1], nil, metadata)
+	assert.Equal(t, http.StatusNoContent, response.Code, "http code of delete by admin must match")
+
+	// make request
+	response = makeTestRequest(s.router, http.MethodGet, "/api/v1/webhooks", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	assert.JSONEq(t, `{"data": []}`, response.Body.String(), "http body must match")
+}
+
+func (s *WebhooksSuite) TestDeleteWebhook_InvalidID() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodDelete, "/api/v1/webhooks/foo", nil, metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusBadRequest, response.Code, "http code must match")
+
+	// assert HTTP body
+	assert.JSONEq(t, `{"error": "invalid webhookID"}`, response.Body.String(), "http body must match")
+}
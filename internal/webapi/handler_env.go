@@ -1,7 +1,15 @@
 package webapi
 
-import "github.com/jmoiron/sqlx"
+import (
+	"github.com/jmoiron/sqlx"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+)
 
 type HandlerEnv struct {
-	DbConn *sqlx.DB
+	DbConn          *sqlx.DB
+	SearchConf      *config.Search
+	IdempotencyConf *config.Idempotency
+	EventsConf      *config.Events
+	WebhooksConf    *config.Webhooks
 }
@@ -0,0 +1,99 @@
+package webapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+const (
+	IdempotencyKeyHeader    = "Idempotency-Key"
+	MaxIdempotencyKeyLength = 255
+	DefaultIdempotencyTTL   = 24 * time.Hour
+)
+
+// idempotencyTTL returns how long responses are replayed, DefaultIdempotencyTTL is used if it is not configured.
+func idempotencyTTL(idempotencyConf *config.Idempotency) time.Duration {
+	if idempotencyConf == nil || idempotencyConf.TTL <= 0 {
+		return DefaultIdempotencyTTL
+	}
+
+	return idempotencyConf.TTL
+}
+
+// requestHash hashes decoded request, so formatting of the body does not matter.
+func requestHash(input any) (string, error) {
+	encoded, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// encodeResponseBody encodes the body the same way as makeJSONResponse does.
+func encodeResponseBody(respBody *ResponseBody) ([]byte, error) {
+	encoded, err := json.Marshal(respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(encoded, '\n'), nil
+}
+
+// replayIdempotentResponse responds with the saved response of the key and reports whether it responded.
+// 422 is returned if the key was used with another request.
+func (h *HandlerEnv) replayIdempotentResponse(
+	ctx context.Context, w http.ResponseWriter, key, hash string,
+) bool {
+	logger := logging.FromContext(ctx).WithField("idempotency_key", key)
+	idempotencyKey, err := db.GetIdempotencyKey(ctx, h.DbConn, key, authn.Actor(ctx), time.Now().UTC())
+	if errors.Is(err, db.ErrIdempotencyKeyNotFound) {
+		return false
+	}
+	if err != nil {
+		logger.WithError(err).Error("get idempotency key failed")
+		InternalServerError(ctx, w, "get idempotency key failed")
+
+		return true
+	}
+	if idempotencyKey.RequestHash != hash {
+		logger.Warn("idempotency key reused with another request")
+		UnprocessableEntity(ctx, w, "idempotency key was used with another request", nil)
+
+		return true
+	}
+
+	logger.Info("replay response of idempotency key")
+	if idempotencyKey.ResponseETag != "" {
+		w.Header().Set("ETag", idempotencyKey.ResponseETag)
+	}
+	makeRawJSONResponse(ctx, w, idempotencyKey.ResponseStatus, idempotencyKey.ResponseBody)
+
+	return true
+}
+
+// createCompanyIdempotently creates the company and saves its response by the key in one transaction.
+func (h *HandlerEnv) createCompanyIdempotently(
+	ctx context.Context, dbCompany *db.Company, idempotencyKey *db.IdempotencyKey,
+) error {
+	return db.WithTx(ctx, h.DbConn, func(tx *sqlx.Tx) error {
+		if err := createCompany(ctx, tx, dbCompany); err != nil {
+			return err
+		}
+
+		return db.SaveIdempotencyKey(ctx, tx, idempotencyKey)
+	})
+}
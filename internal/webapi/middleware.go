@@ -1,6 +1,7 @@
 package webapi
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"github.com/pzabolotniy/logging/pkg/logging"
 
 	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/geoip"
 )
 
@@ -79,6 +81,15 @@ func WithCountryRestriction(
 	return httpMw
 }
 
+type requestIDCtxKey struct{}
+
+// RequestIDFromContext returns the ID generated by WithXRequestID, uuid.Nil if there is no one.
+func RequestIDFromContext(ctx context.Context) uuid.UUID {
+	requestID, _ := ctx.Value(requestIDCtxKey{}).(uuid.UUID)
+
+	return requestID
+}
+
 func WithXRequestID(next http.Handler) http.Handler {
 	handlerFn := func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -86,6 +97,7 @@ func WithXRequestID(next http.Handler) http.Handler {
 		traceID := uuid.New()
 		logger = logger.WithField("x_request_id", traceID)
 		ctx = logging.WithContext(ctx, logger)
+		ctx = context.WithValue(ctx, requestIDCtxKey{}, traceID)
 		r = r.WithContext(ctx)
 		next.ServeHTTP(w, r)
 	}
@@ -107,13 +119,17 @@ func WithAuthN(tokenService authn.TokenValidator) func(next http.Handler) http.H
 				return
 			}
 			clientToken := headerParts[1]
-			_, err := tokenService.ValidateToken(clientToken)
+			claims, err := tokenService.ValidateToken(clientToken)
 			if err != nil {
 				logger.WithError(err).Error("validate token failed")
 				Unauthorized(ctx, w, "invalid token")
 
 				return
 			}
+			ctx = authn.WithClientToken(ctx, claims)
+			// clients without tenant and anonymous clients work with the default tenant
+			ctx = db.WithTenantID(ctx, claims.TenantID)
+			r = r.WithContext(ctx)
 			next.ServeHTTP(w, r)
 		}
 
@@ -122,3 +138,24 @@ func WithAuthN(tokenService authn.TokenValidator) func(next http.Handler) http.H
 
 	return httpMw
 }
+
+// WithOptionalAuthN lets anonymous requests in,
+// but requests with Authorization header must have valid token.
+func WithOptionalAuthN(tokenService authn.TokenValidator) func(next http.Handler) http.Handler {
+	authNMw := WithAuthN(tokenService)
+	httpMw := func(next http.Handler) http.Handler {
+		authNHandler := authNMw(next)
+		handlerFn := func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+			authNHandler.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(handlerFn)
+	}
+
+	return httpMw
+}
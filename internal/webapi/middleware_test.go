@@ -8,11 +8,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/pzabolotniy/logging/pkg/logging"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
 )
 
@@ -144,3 +148,122 @@ func TestWithCountryRestriction_CountryMisMatch(t *testing.T) {
 	expectedBody := `{"error": "access denied"}`
 	assert.JSONEq(t, expectedBody, string(gotResponseBody), "response body must match")
 }
+
+type ClientTokenHandler struct {
+	ctx context.Context
+}
+
+func (h *ClientTokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	OKResponse(h.ctx, w, map[string]any{"admin": authn.IsAdmin(r.Context()), "tenant_id": db.TenantID(r.Context())})
+}
+
+func TestWithOptionalAuthN_Anonymous(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.GetLogger()
+	ctx = logging.WithContext(ctx, logger)
+
+	tokenService := authn.NewTokenService(&config.ClientToken{TTL: time.Hour, Issuer: "test", Secret: "secret"})
+	handlerFn := WithOptionalAuthN(tokenService)
+
+	testRecorder := httptest.NewRecorder()
+	testRequest := httptest.NewRequest(http.MethodGet, "/any", nil)
+	testRequest = testRequest.WithContext(ctx)
+	handlerFn(&ClientTokenHandler{ctx: ctx}).ServeHTTP(testRecorder, testRequest)
+
+	gotHttpCode := testRecorder.Code
+	gotResponseBody, err := io.ReadAll(testRecorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHttpCode, "http code must match")
+
+	expectedBody := `{"data": {"admin": false, "tenant_id": "default"}}`
+	assert.JSONEq(t, expectedBody, string(gotResponseBody), "response body must match")
+}
+
+func TestWithOptionalAuthN_Admin(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.GetLogger()
+	ctx = logging.WithContext(ctx, logger)
+
+	tokenService := authn.NewTokenService(&config.ClientToken{TTL: time.Hour, Issuer: "test", Secret: "secret"})
+	adminJWT, err := tokenService.IssueToken(authn.AsAdmin())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlerFn := WithOptionalAuthN(tokenService)
+
+	testRecorder := httptest.NewRecorder()
+	testRequest := httptest.NewRequest(http.MethodGet, "/any", nil)
+	testRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %s", adminJWT))
+	testRequest = testRequest.WithContext(ctx)
+	handlerFn(&ClientTokenHandler{ctx: ctx}).ServeHTTP(testRecorder, testRequest)
+
+	gotHttpCode := testRecorder.Code
+	gotResponseBody, err := io.ReadAll(testRecorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHttpCode, "http code must match")
+
+	expectedBody := `{"data": {"admin": true, "tenant_id": "default"}}`
+	assert.JSONEq(t, expectedBody, string(gotResponseBody), "response body must match")
+}
+
+func TestWithAuthN_Tenant(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.GetLogger()
+	ctx = logging.WithContext(ctx, logger)
+
+	tokenService := authn.NewTokenService(&config.ClientToken{TTL: time.Hour, Issuer: "test", Secret: "secret"})
+	tenantJWT, err := tokenService.IssueToken(authn.WithTenant("acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlerFn := WithAuthN(tokenService)
+
+	testRecorder := httptest.NewRecorder()
+	testRequest := httptest.NewRequest(http.MethodGet, "/any", nil)
+	testRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tenantJWT))
+	testRequest = testRequest.WithContext(ctx)
+	handlerFn(&ClientTokenHandler{ctx: ctx}).ServeHTTP(testRecorder, testRequest)
+
+	gotHttpCode := testRecorder.Code
+	gotResponseBody, err := io.ReadAll(testRecorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHttpCode, "http code must match")
+
+	expectedBody := `{"data": {"admin": false, "tenant_id": "acme"}}`
+	assert.JSONEq(t, expectedBody, string(gotResponseBody), "response body must match")
+}
+
+func TestWithOptionalAuthN_InvalidToken(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.GetLogger()
+	ctx = logging.WithContext(ctx, logger)
+
+	tokenService := authn.NewTokenService(&config.ClientToken{TTL: time.Hour, Issuer: "test", Secret: "secret"})
+	handlerFn := WithOptionalAuthN(tokenService)
+
+	testRecorder := httptest.NewRecorder()
+	testRequest := httptest.NewRequest(http.MethodGet, "/any", nil)
+	testRequest.Header.Set("Authorization", "Bearer foo")
+	testRequest = testRequest.WithContext(ctx)
+	handlerFn(nil).ServeHTTP(testRecorder, testRequest)
+
+	gotHttpCode := testRecorder.Code
+	gotResponseBody, err := io.ReadAll(testRecorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedHTTPCode := http.StatusUnauthorized
+	assert.Equal(t, expectedHTTPCode, gotHttpCode, "http code must match")
+
+	expectedBody := `{"error": "invalid token"}`
+	assert.JSONEq(t, expectedBody, string(gotResponseBody), "response body must match")
+}
@@ -0,0 +1,168 @@
+package webapi
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+type PatchCompanyResponse struct {
+	CompanyResponse
+}
+
+// patchMediaTypes are accepted media types of the patch, application/json is accepted as JSON Merge Patch as well.
+var patchMediaTypes = map[string]bool{
+	"application/merge-patch+json": true,
+	"application/json":             true,
+}
+
+// PatchCompany applies JSON Merge Patch (RFC 7396) to the company.
+// Only InputCompany fields can be patched, ID and created_at are kept as is.
+func (h *HandlerEnv) PatchCompany(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !patchMediaTypes[mediaType] {
+		logger.WithField("content_type", r.Header.Get("Content-Type")).Warn("unsupported content type")
+		UnsupportedMediaType(ctx, w, "content type must be application/merge-patch+json or application/json")
+
+		return
+	}
+	patch := make(map[string]any)
+	err = json.NewDecoder(r.Body).Decode(&patch)
+	if err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+
+	dbCompany, ok := getCompanyForWrite(ctx, w, r, dbConn, companyID)
+	if !ok {
+		return
+	}
+
+	before := *dbCompany
+	err = applyCompanyPatch(dbCompany, patch)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Warn("apply patch failed")
+		BadRequest(ctx, w, "invalid patch")
+
+		return
+	}
+	if input := newInputCompany(dbCompany); !isValidCompany(ctx, w, &input) {
+		return
+	}
+	dbCompany.UpdatedAt = NewUpdatedAt()
+	dbCompany.UpdatedBy = authn.Actor(ctx)
+
+	err = db.WithTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+		if updateErr := db.UpdateCompany(ctx, tx, dbCompany); updateErr != nil {
+			return updateErr
+		}
+
+		return recordCompanyChanges(ctx, tx, db.CompanyAuditUpdate, companyChange{Before: &before, After: dbCompany})
+	})
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("update company failed")
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			NotFound(ctx, w, "company not found")
+		case errors.Is(err, db.ErrCompanyVersionMismatch):
+			PreconditionFailed(ctx, w, "company was modified")
+		default:
+			if !companyConflict(ctx, w, dbConn, err) {
+				InternalServerError(ctx, w, "update company failed")
+			}
+		}
+
+		return
+	}
+
+	response := &PatchCompanyResponse{CompanyResponse: newCompanyResponse(dbCompany)}
+	w.Header().Set("ETag", companyETag(dbCompany.Version))
+	OKResponse(ctx, w, response)
+}
+
+// applyCompanyPatch merges patch into the company input fields and decodes the result back,
+// so unknown fields and values of the wrong type are rejected.
+func applyCompanyPatch(dbCompany *db.Company, patch map[string]any) error {
+	encodedInput, err := json.Marshal(newInputCompany(dbCompany))
+	if err != nil {
+		return fmt.Errorf("encode company failed: %w", err)
+	}
+	target := make(map[string]any)
+	if err = json.Unmarshal(encodedInput, &target); err != nil {
+		return fmt.Errorf("decode company failed: %w", err)
+	}
+
+	encodedPatched, err := json.Marshal(mergePatch(target, patch))
+	if err != nil {
+		return fmt.Errorf("encode patched company failed: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encodedPatched))
+	decoder.DisallowUnknownFields()
+	input := new(InputCompany)
+	if err = decoder.Decode(input); err != nil {
+		return fmt.Errorf("decode patched company failed: %w", err)
+	}
+	dbCompany.Name = input.Name
+	dbCompany.Code = input.Code
+	dbCompany.Country = strings.ToUpper(input.Country)
+	dbCompany.WebSite = input.WebSite
+	dbCompany.Phone = input.Phone
+	dbCompany.Description = input.Description
+	dbCompany.EmployeesCount = input.EmployeesCount
+	dbCompany.Registered = input.Registered
+	dbCompany.Type = input.Type
+
+	return nil
+}
+
+// mergePatch implements MergePatch function from RFC 7396 for JSON objects.
+func mergePatch(target, patch map[string]any) map[string]any {
+	if target == nil {
+		target = make(map[string]any)
+	}
+	for name, value := range patch {
+		if value == nil {
+			delete(target, name)
+
+			continue
+		}
+		patchObject, isObject := value.(map[string]any)
+		if !isObject {
+			target[name] = value
+
+			continue
+		}
+		targetObject, _ := target[name].(map[string]any)
+		target[name] = mergePatch(targetObject, patchObject)
+	}
+
+	return target
+}
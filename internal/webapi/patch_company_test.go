@@ -0,0 +1,359 @@
+package webapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type PatchCompanySuite struct {
+	dbSuite
+	countryDetectorMock *geoipMocks.CountryDetector
+	router              *chi.Mux
+	testJWT             string
+}
+
+func TestPatchCompanySuite(t *testing.T) {
+	s := new(PatchCompanySuite)
+	suite.Run(t, s)
+}
+
+func (s *PatchCompanySuite) SetupSuite() {
+	s.dbSuite.SetupSuite()
+	s.loadFixtures("fixtures/companies.yaml")
+}
+
+func (s *PatchCompanySuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil)
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("patch-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *PatchCompanySuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *PatchCompanySuite) TestPatchCompany_OK() {
+	t := s.T()
+
+	// prepare fake variadic parameters
+	fakeTime := time.Date(2022, 9, 18, 11, 30, 0, 0, time.UTC)
+	timePatch := gomonkey.ApplyFunc(NewUpdatedAt, func() time.Time {
+		return fakeTime
+	})
+	defer timePatch.Reset()
+
+	// testdata
+	companyID := "9d1c33ff-5a31-4c35-8d3a-4f5bb8b1c0a1"
+	inputData := strings.NewReader(`{
+	"name": "TestPatchCompany_OK patched",
+	"country": "cy",
+	"phone": "+99887766",
+	"employees_count": 42,
+	"registered": true
+}`)
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"Content-Type":  "application/merge-patch+json",
+			"If-Match":      `"1"`,
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodPatch, testURL, inputData, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+	assert.Equal(t, `"2"`, response.Header().Get("ETag"), "etag must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"data": {
+		"id": "9d1c33ff-5a31-4c35-8d3a-4f5bb8b1c0a1",
+		"name": "TestPatchCompany_OK patched",
+		"code": "PATCH",
+		"country": "CY",
+		"website": "https://mars.red",
+		"phone": "+99887766",
+		"description": "",
+		"employees_count": 42,
+		"registered": true,
+		"type": "Corporation",
+		"created_at": "2022-09-17T10:00:00Z",
+		"updated_at": "2022-09-18T11:30:00Z",
+		"version": 2,
+		"tags": [],
+		"created_by": "",
+		"updated_by": "test/patch-tester"
+	}
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+
+	// assert db values
+	dbCompany := selectDbCompanyByID(t, s.dbConn, companyID)
+	expectedDbCompany := &db.Company{
+		Name:      "TestPatchCompany_OK patched",
+		Code:      "PATCH",
+		Country:   "CY",
+		WebSite:   "https://mars.red",
+		CreatedAt: time.Date(2022, 9, 17, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: fakeTime,
+		Phone:     "+99887766",
+		ID:        uuid.MustParse(companyID),
+		Version:   2,
+
+		EmployeesCount: 42,
+		Registered:     true,
+		Type:           db.CompanyTypeCorporation,
+	}
+	assert.Equal(t, expectedDbCompany, dbCompany, "db company must match")
+}
+
+func (s *PatchCompanySuite) TestPatchCompany_NotFound() {
+	t := s.T()
+
+	// testdata
+	companyID := "9d1c33ff-5a31-4c35-8d3a-4f5bb8b1c999"
+	inputData := strings.NewReader(`{"name": "TestPatchCompany_NotFound"}`)
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"Content-Type":  "application/merge-patch+json",
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodPatch, testURL, inputData, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusNotFound
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "company not found"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PatchCompanySuite) TestPatchCompany_UnsupportedMediaType() {
+	t := s.T()
+
+	// testdata
+	companyID := "9d1c33ff-5a31-4c35-8d3a-4f5bb8b1c999"
+	inputData := strings.NewReader(`[{"op": "replace", "path": "/name", "value": "json patch"}]`)
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"Content-Type":  "application/json-patch+json",
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodPatch, testURL, inputData, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusUnsupportedMediaType
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "content type must be application/merge-patch+json or application/json"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PatchCompanySuite) TestPatchCompany_UnknownField() {
+	t := s.T()
+
+	// testdata
+	companyID := "9d1c33ff-5a31-4c35-8d3a-4f5bb8b1c0a1"
+	inputData := strings.NewReader(`{"created_at": "2020-01-01T00:00:00Z"}`)
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"Content-Type":  "application/merge-patch+json",
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodPatch, testURL, inputData, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusBadRequest
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "invalid patch"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PatchCompanySuite) TestPatchCompany_ValidationFailed() {
+	t := s.T()
+
+	// testdata
+	companyID := "9d1c33ff-5a31-4c35-8d3a-4f5bb8b1c0a2"
+	inputData := strings.NewReader(`{"name": null, "website": "mars"}`)
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"Content-Type":  "application/merge-patch+json",
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodPatch, testURL, inputData, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusUnprocessableEntity
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "invalid company",
+	"details": [
+		{"field": "name", "message": "is required"},
+		{"field": "website", "message": "must be absolute http(s) URL"}
+	]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PatchCompanySuite) TestPatchCompany_PreconditionFailed() {
+	t := s.T()
+
+	// testdata
+	companyID := "9d1c33ff-5a31-4c35-8d3a-4f5bb8b1c0a2"
+	inputData := strings.NewReader(`{"name": "TestPatchCompany_PreconditionFailed patched"}`)
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"Content-Type":  "application/merge-patch+json",
+			"If-Match":      `"42"`,
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s", companyID)
+	response := makeTestRequest(s.router, http.MethodPatch, testURL, inputData, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusPreconditionFailed
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "company was modified"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+
+	// assert db values
+	dbCompany := selectDbCompanyByID(t, s.dbConn, companyID)
+	assert.Equal(t, "TestPatchCompany_PreconditionFailed", dbCompany.Name, "db company name must not change")
+}
+
+func TestMergePatch(t *testing.T) {
+	target := map[string]any{
+		"a": "b",
+		"c": map[string]any{
+			"d": "e",
+			"f": "g",
+		},
+	}
+	patch := map[string]any{
+		"a": "z",
+		"c": map[string]any{
+			"f": nil,
+		},
+		"h": []any{"i"},
+	}
+
+	got := mergePatch(target, patch)
+
+	expected := map[string]any{
+		"a": "z",
+		"c": map[string]any{
+			"d": "e",
+		},
+		"h": []any{"i"},
+	}
+	assert.Equal(t, expected, got, "patched object must match")
+}
@@ -1,13 +1,19 @@
 package webapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/pzabolotniy/logging/pkg/logging"
 
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
 )
 
@@ -17,12 +23,58 @@ type InputCompany struct {
 	Country string `json:"country"`
 	WebSite string `json:"website"` //nolint:tagliatelle // need to discuss
 	Phone   string `json:"phone"`
+	// Description is up to 3000 characters.
+	Description    string         `json:"description"`
+	EmployeesCount int            `json:"employees_count"` //nolint:tagliatelle // false positive
+	Registered     bool           `json:"registered"`
+	Type           db.CompanyType `json:"type"`
 }
 
 type CompanyResponse struct {
-	CreatedAt time.Time `json:"created_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 	InputCompany
-	ID uuid.UUID `json:"id"`
+	ID      uuid.UUID `json:"id"`
+	Version int64     `json:"version"`
+	// ParentID is the parent company of the subsidiary, it is changed by PutCompanyParent only.
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	// Tags are changed by PostCompanyTags and DeleteCompanyTag only.
+	Tags []string `json:"tags"`
+	// CreatedBy and UpdatedBy are actors of the clients created and last changed the company,
+	// they are empty for companies created before the actors were recorded.
+	CreatedBy string `json:"created_by"`
+	UpdatedBy string `json:"updated_by"`
+}
+
+func newInputCompany(dbCompany *db.Company) InputCompany {
+	return InputCompany{
+		Name:    dbCompany.Name,
+		Code:    dbCompany.Code,
+		Country: dbCompany.Country,
+		WebSite: dbCompany.WebSite,
+		Phone:   dbCompany.Phone,
+
+		Description:    dbCompany.Description,
+		EmployeesCount: dbCompany.EmployeesCount,
+		Registered:     dbCompany.Registered,
+		Type:           dbCompany.Type,
+	}
+}
+
+func newCompanyResponse(dbCompany *db.Company) CompanyResponse {
+	return CompanyResponse{
+		CreatedAt:    dbCompany.CreatedAt,
+		UpdatedAt:    dbCompany.UpdatedAt,
+		DeletedAt:    dbCompany.DeletedAt,
+		InputCompany: newInputCompany(dbCompany),
+		ID:           dbCompany.ID,
+		Version:      dbCompany.Version,
+		ParentID:     dbCompany.ParentID,
+		Tags:         newTagsResponse(dbCompany.Tags),
+		CreatedBy:    dbCompany.CreatedBy,
+		UpdatedBy:    dbCompany.UpdatedBy,
+	}
 }
 
 type PostCompanyResponse struct {
@@ -42,40 +94,141 @@ func (h *HandlerEnv) PostCompanies(w http.ResponseWriter, r *http.Request) {
 
 		return
 	}
+	if !isValidCompany(ctx, w, input) {
+		return
+	}
+	if idempotencyKey := r.Header.Get(IdempotencyKeyHeader); idempotencyKey != "" {
+		h.postCompanyIdempotently(ctx, w, idempotencyKey, input)
 
-	companyID := NewCompanyID()
-	createdAt := NewCreatedAt()
-	dbCompany := &db.Company{
-		ID:        companyID,
-		Name:      input.Name,
-		Code:      input.Code,
-		Country:   input.Country,
-		WebSite:   input.WebSite,
-		Phone:     input.Phone,
-		CreatedAt: createdAt,
+		return
 	}
-	err = db.CreateCompany(ctx, dbConn, dbCompany)
+
+	dbCompany := newDbCompany(NewCompanyID(), NewCreatedAt(), authn.Actor(ctx), input)
+	err = db.WithTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+		return createCompany(ctx, tx, dbCompany)
+	})
 	if err != nil {
 		logger.WithError(err).Error("create company failed")
-		InternalServerError(ctx, w, "create company failed")
+		if !companyConflict(ctx, w, dbConn, err) {
+			InternalServerError(ctx, w, "create company failed")
+		}
 
 		return
 	}
 
 	response := &PostCompanyResponse{
-		CompanyResponse: CompanyResponse{
-			InputCompany: *input,
-			ID:           companyID,
-			CreatedAt:    createdAt,
-		},
+		CompanyResponse: newCompanyResponse(dbCompany),
 	}
+	w.Header().Set("ETag", companyETag(dbCompany.Version))
 	CreatedResponse(ctx, w, response)
 }
 
+// createCompany creates the company and records its creation in the company history.
+func createCompany(ctx context.Context, tx *sqlx.Tx, dbCompany *db.Company) error {
+	if err := db.CreateCompany(ctx, tx, dbCompany); err != nil {
+		return err
+	}
+
+	return recordCompanyChanges(ctx, tx, db.CompanyAuditCreate, companyChange{After: dbCompany})
+}
+
+// postCompanyIdempotently creates the company once per key,
+// retries with the same key and request get the response of the first request.
+func (h *HandlerEnv) postCompanyIdempotently(ctx context.Context, w http.ResponseWriter, key string, input *InputCompany) {
+	logger := logging.FromContext(ctx).WithField("idempotency_key", key)
+
+	if len(key) > MaxIdempotencyKeyLength {
+		logger.Warn("too long idempotency key")
+		BadRequest(ctx, w, fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, MaxIdempotencyKeyLength))
+
+		return
+	}
+	hash, err := requestHash(input)
+	if err != nil {
+		logger.WithError(err).Error("hash request failed")
+		InternalServerError(ctx, w, "create company failed")
+
+		return
+	}
+	if h.replayIdempotentResponse(ctx, w, key, hash) {
+		return
+	}
+
+	createdAt := NewCreatedAt()
+	dbCompany := newDbCompany(NewCompanyID(), createdAt, authn.Actor(ctx), input)
+	response := &PostCompanyResponse{
+		CompanyResponse: newCompanyResponse(dbCompany),
+	}
+	responseBody, err := encodeResponseBody(&ResponseBody{Data: response})
+	if err != nil {
+		logger.WithError(err).Error("encode response failed")
+		InternalServerError(ctx, w, "create company failed")
+
+		return
+	}
+	idempotencyKey := &db.IdempotencyKey{
+		Key:            key,
+		RequestHash:    hash,
+		ResponseStatus: http.StatusCreated,
+		ResponseBody:   responseBody,
+		ResponseETag:   companyETag(dbCompany.Version),
+		CreatedAt:      createdAt,
+		ExpiresAt:      createdAt.Add(idempotencyTTL(h.IdempotencyConf)),
+		Actor:          authn.Actor(ctx),
+	}
+	err = h.createCompanyIdempotently(ctx, dbCompany, idempotencyKey)
+	if errors.Is(err, db.ErrIdempotencyKeyExists) {
+		// concurrent request with the same key has won
+		logger.WithError(err).Warn("create company failed")
+		if !h.replayIdempotentResponse(ctx, w, key, hash) {
+			InternalServerError(ctx, w, "create company failed")
+		}
+
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("create company failed")
+		if !companyConflict(ctx, w, h.DbConn, err) {
+			InternalServerError(ctx, w, "create company failed")
+		}
+
+		return
+	}
+
+	w.Header().Set("ETag", idempotencyKey.ResponseETag)
+	makeRawJSONResponse(ctx, w, idempotencyKey.ResponseStatus, idempotencyKey.ResponseBody)
+}
+
+// newDbCompany makes the first version of the company created by the actor createdBy.
+func newDbCompany(companyID uuid.UUID, createdAt time.Time, createdBy string, input *InputCompany) *db.Company {
+	return &db.Company{
+		ID:        companyID,
+		Name:      input.Name,
+		Code:      input.Code,
+		Country:   strings.ToUpper(input.Country),
+		WebSite:   input.WebSite,
+		Phone:     input.Phone,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		CreatedBy: createdBy,
+		UpdatedBy: createdBy,
+		Version:   db.FirstCompanyVersion,
+
+		Description:    input.Description,
+		EmployeesCount: input.EmployeesCount,
+		Registered:     input.Registered,
+		Type:           input.Type,
+	}
+}
+
 func NewCreatedAt() time.Time {
 	return time.Now().UTC()
 }
 
+func NewUpdatedAt() time.Time {
+	return time.Now().UTC()
+}
+
 func NewCompanyID() uuid.UUID {
 	return uuid.New()
 }
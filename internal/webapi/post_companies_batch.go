@@ -0,0 +1,259 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// MaxCompaniesBatchSize keeps the multi-row insert below the limit of query parameters.
+const MaxCompaniesBatchSize = 1000
+
+type CompaniesBatchMode string
+
+const (
+	// BatchModeAtomic creates all companies or none of them.
+	BatchModeAtomic CompaniesBatchMode = "atomic"
+	// BatchModePartial creates valid not conflicting companies and reports the status of each one.
+	BatchModePartial CompaniesBatchMode = "partial"
+)
+
+type CompaniesBatchRequest struct {
+	Mode      CompaniesBatchMode `json:"mode"`
+	Companies []InputCompany     `json:"companies"`
+}
+
+// BatchItemErrors are validation errors of the company at Index of the batch.
+type BatchItemErrors struct {
+	Index  int         `json:"index"`
+	Errors FieldErrors `json:"errors"`
+}
+
+// BatchItemConflict names the company at Index of the batch conflicting with the existing company
+// or with the company at DuplicateOf index of the same batch.
+type BatchItemConflict struct {
+	ExistingCompanyID *uuid.UUID `json:"existing_company_id,omitempty"`
+	DuplicateOf       *int       `json:"duplicate_of,omitempty"`
+	Index             int        `json:"index"`
+}
+
+// BatchItemResult is the result of the company at Index of the batch in partial mode,
+// Status is HTTP status the company would get from PostCompanies.
+type BatchItemResult struct {
+	Company *CompanyResponse `json:"company,omitempty"`
+	Errors  FieldErrors      `json:"errors,omitempty"`
+	// ExistingCompanyID is the company having the same code and country if Status is 409.
+	ExistingCompanyID *uuid.UUID `json:"existing_company_id,omitempty"`
+	Index             int        `json:"index"`
+	Status            int        `json:"status"`
+}
+
+// PostCompaniesBatch creates companies by one batched insert.
+// In atomic mode (default) any invalid or conflicting company fails the whole batch,
+// in partial mode the status of every company is reported.
+func (h *HandlerEnv) PostCompaniesBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	input := new(CompaniesBatchRequest)
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+	if len(input.Companies) == 0 || len(input.Companies) > MaxCompaniesBatchSize {
+		logger.WithField("companies_count", len(input.Companies)).Warn("invalid batch size")
+		BadRequest(ctx, w, fmt.Sprintf("companies must contain from 1 to %d companies", MaxCompaniesBatchSize))
+
+		return
+	}
+
+	switch input.Mode {
+	case BatchModeAtomic, "":
+		h.postCompaniesBatchAtomic(ctx, w, input.Companies)
+	case BatchModePartial:
+		h.postCompaniesBatchPartial(ctx, w, input.Companies)
+	default:
+		logger.WithField("mode", input.Mode).Warn("invalid batch mode")
+		BadRequest(ctx, w, fmt.Sprintf("mode must be %s or %s", BatchModeAtomic, BatchModePartial))
+	}
+}
+
+func (h *HandlerEnv) postCompaniesBatchAtomic(ctx context.Context, w http.ResponseWriter, inputs []InputCompany) {
+	logger := logging.FromContext(ctx)
+
+	invalidItems := make([]BatchItemErrors, 0)
+	for i := range inputs {
+		if validationErrs := inputs[i].Validate(); len(validationErrs) > 0 {
+			invalidItems = append(invalidItems, BatchItemErrors{Index: i, Errors: validationErrs})
+		}
+	}
+	if len(invalidItems) > 0 {
+		logger.WithField("invalid_companies", invalidItems).Warn("invalid companies")
+		UnprocessableEntity(ctx, w, "invalid companies", invalidItems)
+
+		return
+	}
+
+	dbCompanies := newBatchDbCompanies(inputs, authn.Actor(ctx))
+	err := db.WithTx(ctx, h.DbConn, func(tx *sqlx.Tx) error {
+		if createErr := db.CreateCompanies(ctx, tx, dbCompanies); createErr != nil {
+			return createErr
+		}
+		changes := make([]companyChange, 0, len(dbCompanies))
+		for i := range dbCompanies {
+			changes = append(changes, companyChange{After: &dbCompanies[i]})
+		}
+
+		return recordCompanyChanges(ctx, tx, db.CompanyAuditCreate, changes...)
+	})
+	var conflictErr *db.CompanyConflictError
+	if errors.As(err, &conflictErr) {
+		logger.WithError(err).Warn("create companies failed")
+		h.batchConflict(ctx, w, dbCompanies)
+
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("create companies failed")
+		InternalServerError(ctx, w, "create companies failed")
+
+		return
+	}
+
+	response := make([]CompanyResponse, 0, len(dbCompanies))
+	for i := range dbCompanies {
+		response = append(response, newCompanyResponse(&dbCompanies[i]))
+	}
+	CreatedResponse(ctx, w, response)
+}
+
+func (h *HandlerEnv) postCompaniesBatchPartial(ctx context.Context, w http.ResponseWriter, inputs []InputCompany) {
+	logger := logging.FromContext(ctx)
+
+	results := make([]BatchItemResult, len(inputs))
+	validInputs := make([]InputCompany, 0, len(inputs))
+	validIndexes := make([]int, 0, len(inputs))
+	for i := range inputs {
+		results[i].Index = i
+		if validationErrs := inputs[i].Validate(); len(validationErrs) > 0 {
+			results[i].Status = http.StatusUnprocessableEntity
+			results[i].Errors = validationErrs
+
+			continue
+		}
+		validInputs = append(validInputs, inputs[i])
+		validIndexes = append(validIndexes, i)
+	}
+
+	dbCompanies := newBatchDbCompanies(validInputs, authn.Actor(ctx))
+	var createdIDs map[uuid.UUID]bool
+	err := db.WithTx(ctx, h.DbConn, func(tx *sqlx.Tx) error {
+		var createErr error
+		createdIDs, createErr = db.CreateCompaniesSkipConflicts(ctx, tx, dbCompanies)
+		if createErr != nil {
+			return createErr
+		}
+		changes := make([]companyChange, 0, len(createdIDs))
+		for i := range dbCompanies {
+			if createdIDs[dbCompanies[i].ID] {
+				changes = append(changes, companyChange{After: &dbCompanies[i]})
+			}
+		}
+
+		return recordCompanyChanges(ctx, tx, db.CompanyAuditCreate, changes...)
+	})
+	if err != nil {
+		logger.WithError(err).Error("create companies failed")
+		InternalServerError(ctx, w, "create companies failed")
+
+		return
+	}
+
+	// companies are committed, so duplicates of the batch are found as existing ones
+	conflictCodes := make([]db.CompanyCode, 0)
+	for i := range dbCompanies {
+		if !createdIDs[dbCompanies[i].ID] {
+			conflictCodes = append(conflictCodes, companyCode(dbCompanies[i].Code, dbCompanies[i].Country))
+		}
+	}
+	existingIDs, err := existingCompanyIDs(ctx, h.DbConn, conflictCodes)
+	if err != nil {
+		// the companies are created, the conflicts are reported without IDs
+		logger.WithError(err).Warn("get conflicting companies failed")
+	}
+	for i := range dbCompanies {
+		result := &results[validIndexes[i]]
+		if !createdIDs[dbCompanies[i].ID] {
+			result.Status = http.StatusConflict
+			if existingID, ok := existingIDs[companyCode(dbCompanies[i].Code, dbCompanies[i].Country)]; ok {
+				result.ExistingCompanyID = &existingID
+			}
+
+			continue
+		}
+		companyResponse := newCompanyResponse(&dbCompanies[i])
+		result.Status = http.StatusCreated
+		result.Company = &companyResponse
+	}
+	OKResponse(ctx, w, results)
+}
+
+// batchConflict responds with 409 naming conflicting companies of the batch,
+// it must be called after the transaction of the batch.
+func (h *HandlerEnv) batchConflict(ctx context.Context, w http.ResponseWriter, dbCompanies []db.Company) {
+	logger := logging.FromContext(ctx)
+	firstIndexes := make(map[db.CompanyCode]int, len(dbCompanies))
+	codes := make([]db.CompanyCode, 0, len(dbCompanies))
+	for i := range dbCompanies {
+		code := companyCode(dbCompanies[i].Code, dbCompanies[i].Country)
+		if _, ok := firstIndexes[code]; !ok {
+			firstIndexes[code] = i
+			codes = append(codes, code)
+		}
+	}
+	existingIDs, err := existingCompanyIDs(ctx, h.DbConn, codes)
+	if err != nil {
+		logger.WithError(err).Error("get conflicting companies failed")
+		InternalServerError(ctx, w, "create companies failed")
+
+		return
+	}
+
+	conflicts := make([]BatchItemConflict, 0)
+	for i := range dbCompanies {
+		code := companyCode(dbCompanies[i].Code, dbCompanies[i].Country)
+		firstIndex := firstIndexes[code]
+		existingID, exists := existingIDs[code]
+		switch {
+		case firstIndex != i:
+			conflicts = append(conflicts, BatchItemConflict{Index: i, DuplicateOf: &firstIndex})
+		case exists:
+			conflicts = append(conflicts, BatchItemConflict{Index: i, ExistingCompanyID: &existingID})
+		}
+	}
+	logger.WithField("conflicting_companies", conflicts).Warn("conflicting companies")
+	Conflict(ctx, w, "company with the same code and country already exists", conflicts)
+}
+
+// newBatchDbCompanies makes companies of the batch, all of them have the same creation time.
+func newBatchDbCompanies(inputs []InputCompany, createdBy string) []db.Company {
+	createdAt := NewCreatedAt()
+	dbCompanies := make([]db.Company, 0, len(inputs))
+	for i := range inputs {
+		dbCompanies = append(dbCompanies, *newDbCompany(NewCompanyID(), createdAt, createdBy, &inputs[i]))
+	}
+
+	return dbCompanies
+}
@@ -0,0 +1,177 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// CompaniesFilterInput has the same filters as GetCompanies.
+type CompaniesFilterInput struct {
+	Country      string `json:"country"`
+	CodePrefix   string `json:"code_prefix"`
+	NameContains string `json:"name"` //nolint:tagliatelle // same as query parameter of GetCompanies
+	// CreatedFrom is inclusive.
+	CreatedFrom *time.Time `json:"created_from"`
+	// CreatedTo is exclusive.
+	CreatedTo      *time.Time `json:"created_to"`
+	AddressCity    string     `json:"address_city"`
+	AddressCountry string     `json:"address_country"`
+	Tags           []string   `json:"tags"`
+	// TagsMatch is any (default) or all.
+	TagsMatch db.TagsMatch `json:"tags_match"`
+}
+
+func (f *CompaniesFilterInput) isEmpty() bool {
+	return f.Country == "" && f.CodePrefix == "" && f.NameContains == "" && f.CreatedFrom == nil && f.CreatedTo == nil &&
+		f.AddressCity == "" && f.AddressCountry == "" && len(f.Tags) == 0
+}
+
+func (f *CompaniesFilterInput) dbFilter() (*db.CompanyFilter, error) {
+	filter := &db.CompanyFilter{
+		Country:        f.Country,
+		CodePrefix:     f.CodePrefix,
+		NameContains:   f.NameContains,
+		AddressCity:    f.AddressCity,
+		AddressCountry: f.AddressCountry,
+	}
+	if f.CreatedFrom != nil {
+		createdFrom := f.CreatedFrom.UTC()
+		filter.CreatedFrom = &createdFrom
+	}
+	if f.CreatedTo != nil {
+		createdTo := f.CreatedTo.UTC()
+		filter.CreatedTo = &createdTo
+	}
+	var err error
+	filter.Tags, filter.TagsMatch, err = checkTagsFilter(f.Tags, f.TagsMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+type CompaniesBatchDeleteRequest struct {
+	// CompaniesIDs and Filter select companies to delete, only one of them can be passed.
+	CompaniesIDs []string              `json:"companies_ids"` //nolint:tagliatelle // false positive
+	Filter       *CompaniesFilterInput `json:"filter"`
+	// DryRun reports companies which would be deleted without deleting them.
+	DryRun bool `json:"dry_run"`
+}
+
+type CompaniesBatchDeleteResponse struct {
+	DeletedIDs []uuid.UUID `json:"deleted_ids"` //nolint:tagliatelle // false positive
+	DryRun     bool        `json:"dry_run"`
+}
+
+// PostCompaniesBatchDelete soft deletes companies selected by IDs or by filter in one transaction.
+// Unknown and already deleted companies are skipped, IDs of deleted companies are returned.
+func (h *HandlerEnv) PostCompaniesBatchDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+
+	input := new(CompaniesBatchDeleteRequest)
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+
+	params := &db.DeleteCompaniesParams{DeletedAt: NewUpdatedAt(), DeletedBy: authn.Actor(ctx)}
+	switch {
+	case len(input.CompaniesIDs) > 0 && input.Filter != nil:
+		logger.Warn("both companies_ids and filter passed")
+		BadRequest(ctx, w, "companies_ids and filter can not be combined")
+
+		return
+	case len(input.CompaniesIDs) > 0:
+		if maxIDs := searchMaxIDs(h.SearchConf); maxIDs > 0 && len(input.CompaniesIDs) > maxIDs {
+			logger.WithField("companies_ids_count", len(input.CompaniesIDs)).Warn("too many companies_ids")
+			BadRequest(ctx, w, fmt.Sprintf("too many companies_ids, max is %d", maxIDs))
+
+			return
+		}
+		params.CompanyIDs = make([]uuid.UUID, 0, len(input.CompaniesIDs))
+		invalidIDs := make([]string, 0)
+		for _, inputID := range input.CompaniesIDs {
+			companyID, parseErr := uuid.Parse(inputID)
+			if parseErr != nil {
+				invalidIDs = append(invalidIDs, inputID)
+
+				continue
+			}
+			params.CompanyIDs = append(params.CompanyIDs, companyID)
+		}
+		if len(invalidIDs) > 0 {
+			logger.WithField("invalid_ids", invalidIDs).Warn("parse companies_ids failed")
+			makeJSONResponse(ctx, w, &Response{
+				HTTPStatus: http.StatusBadRequest,
+				HTTPBody:   &ResponseBody{Error: "invalid companies_ids", InvalidIDs: invalidIDs},
+			})
+
+			return
+		}
+	case input.Filter != nil && !input.Filter.isEmpty():
+		params.Filter, err = input.Filter.dbFilter()
+		if err != nil {
+			logger.WithError(err).Warn("invalid filter")
+			BadRequest(ctx, w, err.Error())
+
+			return
+		}
+	default:
+		logger.Warn("neither companies_ids nor filter passed")
+		BadRequest(ctx, w, "companies_ids or not empty filter is required")
+
+		return
+	}
+
+	var selected []db.Company
+	err = db.WithTx(ctx, h.DbConn, func(tx *sqlx.Tx) error {
+		var deleteErr error
+		if input.DryRun {
+			selected, deleteErr = db.SelectCompaniesToDelete(ctx, tx, params)
+
+			return deleteErr
+		}
+		selected, deleteErr = db.DeleteCompanies(ctx, tx, params)
+		if deleteErr != nil {
+			return deleteErr
+		}
+		changes := make([]companyChange, 0, len(selected))
+		for i := range selected {
+			changes = append(changes, companyChange{
+				Before: &selected[i],
+				After:  deletedCompany(&selected[i], params.DeletedAt, params.DeletedBy),
+			})
+		}
+
+		return recordCompanyChanges(ctx, tx, db.CompanyAuditDelete, changes...)
+	})
+	if err != nil {
+		logger.WithError(err).Error("delete companies failed")
+		InternalServerError(ctx, w, "delete companies failed")
+
+		return
+	}
+
+	deletedIDs := make([]uuid.UUID, 0, len(selected))
+	for i := range selected {
+		deletedIDs = append(deletedIDs, selected[i].ID)
+	}
+	logger.
+		WithFields(logging.Fields{"deleted_count": len(deletedIDs), "dry_run": input.DryRun}).
+		Info("companies deleted")
+	OKResponse(ctx, w, &CompaniesBatchDeleteResponse{DeletedIDs: deletedIDs, DryRun: input.DryRun})
+}
@@ -0,0 +1,198 @@
+package webapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type PostCompaniesBatchDeleteSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestPostCompaniesBatchDeleteSuite(t *testing.T) {
+	s := new(PostCompaniesBatchDeleteSuite)
+	suite.Run(t, s)
+}
+
+func (s *PostCompaniesBatchDeleteSuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil)
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken()
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *PostCompaniesBatchDeleteSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *PostCompaniesBatchDeleteSuite) TestPostCompaniesBatchDelete_ByIDs() {
+	t := s.T()
+
+	// prepare existing companies
+	s.insertCompanies("BDEL-IDS", "5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a01", "5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a02")
+
+	// prepare input data, unknown company is skipped
+	inputData := strings.NewReader(`{
+	"companies_ids": [
+		"5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a01",
+		"5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a99"
+	]
+}`)
+
+	// make request
+	response := s.makeBatchDeleteRequest(inputData)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPBody := `{
+	"data": {
+		"deleted_ids": ["5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a01"],
+		"dry_run": false
+	}
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+
+	// assert db values
+	deletedCompany := selectDbCompanyByID(t, s.dbConn, "5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a01")
+	assert.NotNil(t, deletedCompany.DeletedAt, "company must be deleted")
+	assert.Equal(t, int64(2), deletedCompany.Version, "version must be incremented")
+	keptCompany := selectDbCompanyByID(t, s.dbConn, "5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a02")
+	assert.Nil(t, keptCompany.DeletedAt, "company must not be deleted")
+}
+
+func (s *PostCompaniesBatchDeleteSuite) TestPostCompaniesBatchDelete_ByFilterDryRun() {
+	t := s.T()
+
+	// prepare existing companies
+	s.insertCompanies("BDEL-DRY", "5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a11", "5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a12")
+
+	// prepare input data
+	inputData := strings.NewReader(`{
+	"filter": {"code_prefix": "BDEL-DRY"},
+	"dry_run": true
+}`)
+
+	// make request
+	response := s.makeBatchDeleteRequest(inputData)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPBody := `{
+	"data": {
+		"deleted_ids": ["5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a11", "5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a12"],
+		"dry_run": true
+	}
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+
+	// assert nothing is deleted
+	for _, companyID := range []string{"5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a11", "5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a12"} {
+		dbCompany := selectDbCompanyByID(t, s.dbConn, companyID)
+		assert.Nil(t, dbCompany.DeletedAt, "company must not be deleted")
+	}
+}
+
+func (s *PostCompaniesBatchDeleteSuite) TestPostCompaniesBatchDelete_InvalidSelector() {
+	t := s.T()
+
+	testCases := []struct {
+		name         string
+		inputData    string
+		expectedBody string
+	}{
+		{
+			name:         "empty filter",
+			inputData:    `{"filter": {}}`,
+			expectedBody: `{"error": "companies_ids or not empty filter is required"}`,
+		},
+		{
+			name:         "ids and filter",
+			inputData:    `{"companies_ids": ["5f0c1a52-7a1e-4b0e-9a3c-0d6b3e1f2a01"], "filter": {"country": "CY"}}`,
+			expectedBody: `{"error": "companies_ids and filter can not be combined"}`,
+		},
+		{
+			name:         "invalid id",
+			inputData:    `{"companies_ids": ["foo"]}`,
+			expectedBody: `{"error": "invalid companies_ids", "invalid_ids": ["foo"]}`,
+		},
+	}
+	for _, testCase := range testCases {
+		response := s.makeBatchDeleteRequest(strings.NewReader(testCase.inputData))
+		assert.Equal(t, http.StatusBadRequest, response.Code, "http code must match: %s", testCase.name)
+		gotBody, err := io.ReadAll(response.Body)
+		if err != nil {
+			t.Fatalf("read response body failed: %s", err)
+		}
+		assert.JSONEq(t, testCase.expectedBody, string(gotBody), "body must match: %s", testCase.name)
+	}
+}
+
+func (s *PostCompaniesBatchDeleteSuite) makeBatchDeleteRequest(body io.Reader) *httptest.ResponseRecorder {
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	return makeTestRequest(s.router, http.MethodPost, "/api/v1/companies:batchDelete", body, metadata)
+}
+
+// insertCompanies inserts companies with codes made of codePrefix and index.
+func (s *PostCompaniesBatchDeleteSuite) insertCompanies(codePrefix string, companyIDs ...string) {
+	t := s.T()
+	for i, companyID := range companyIDs {
+		_, err := s.dbConn.Exec(`INSERT INTO companies (id, name, code, country, website, phone, type, created_at, updated_at)
+VALUES ($1, $2, $3, 'CY', '', '', 'Corporation', now(), now())`,
+			companyID, t.Name(), fmt.Sprintf("%s-%d", codePrefix, i))
+		if err != nil {
+			t.Fatalf("insert company failed: %s", err)
+		}
+	}
+}
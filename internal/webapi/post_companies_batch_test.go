@@ -0,0 +1,270 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type PostCompaniesBatchSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestPostCompaniesBatchSuite(t *testing.T) {
+	s := new(PostCompaniesBatchSuite)
+	suite.Run(t, s)
+}
+
+func (s *PostCompaniesBatchSuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil)
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken()
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *PostCompaniesBatchSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *PostCompaniesBatchSuite) TestPostCompaniesBatch_AtomicOK() {
+	t := s.T()
+
+	// prepare input data
+	inputData := strings.NewReader(`{
+	"mode": "atomic",
+	"companies": [
+		{"name": "first", "code": "BATCH-OK-1", "country": "CY", "type": "Corporation"},
+		{"name": "second", "code": "BATCH-OK-2", "country": "CY", "type": "NonProfit"}
+	]
+}`)
+
+	// make request
+	response := s.makeBatchRequest(inputData)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusCreated, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data []CompanyResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if !assert.Len(t, gotBody.Data, 2, "companies count must match") {
+		return
+	}
+	assert.Equal(t, "BATCH-OK-1", gotBody.Data[0].Code, "code must match")
+	assert.Equal(t, "BATCH-OK-2", gotBody.Data[1].Code, "code must match")
+
+	// assert db values
+	for i := range gotBody.Data {
+		dbCompany := selectDbCompanyByID(t, s.dbConn, gotBody.Data[i].ID.String())
+		assert.Equal(t, gotBody.Data[i].Name, dbCompany.Name, "db company name must match")
+		assert.Equal(t, int64(db.FirstCompanyVersion), dbCompany.Version, "db company version must match")
+	}
+}
+
+func (s *PostCompaniesBatchSuite) TestPostCompaniesBatch_AtomicValidationFailed() {
+	t := s.T()
+
+	// prepare input data
+	inputData := strings.NewReader(`{
+	"companies": [
+		{"name": "valid", "code": "BATCH-INVALID-1", "country": "CY", "type": "Corporation"},
+		{"name": "", "code": "BATCH-INVALID-2", "country": "CY", "type": "Corporation"}
+	]
+}`)
+
+	// make request
+	response := s.makeBatchRequest(inputData)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPBody := `{
+	"error": "invalid companies",
+	"details": [
+		{"index": 1, "errors": [{"field": "name", "message": "is required"}]}
+	]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+
+	// assert nothing is created
+	s.assertCompaniesCount("BATCH-INVALID-%", 0)
+}
+
+func (s *PostCompaniesBatchSuite) TestPostCompaniesBatch_AtomicConflict() {
+	t := s.T()
+
+	// prepare existing company
+	_, err := s.dbConn.Exec(`INSERT INTO companies (id, name, code, country, website, phone, type, created_at, updated_at)
+VALUES ('8e2f4a6b-1c3d-4e5f-a7b9-c0d1e2f3a4b5', 'TestPostCompaniesBatch_AtomicConflict', 'BATCH-DUP-0', 'CY',
+        '', '', 'Corporation', now(), now())`)
+	if err != nil {
+		t.Fatalf("insert company failed: %s", err)
+	}
+
+	// prepare input data, the third company duplicates the first one, the fourth one conflicts with existing company
+	inputData := strings.NewReader(`{
+	"mode": "atomic",
+	"companies": [
+		{"name": "first", "code": "BATCH-DUP", "country": "CY", "type": "Corporation"},
+		{"name": "second", "code": "BATCH-DUP-2", "country": "CY", "type": "Corporation"},
+		{"name": "third", "code": "BATCH-DUP", "country": "cy", "type": "Corporation"},
+		{"name": "fourth", "code": "BATCH-DUP-0", "country": "cy", "type": "Corporation"}
+	]
+}`)
+
+	// make request
+	response := s.makeBatchRequest(inputData)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusConflict, response.Code, "http code must match")
+
+	// assert HTTP body
+	expectedBody := `{"error":"company with the same code and country already exists","details":[` +
+		`{"index":2,"duplicate_of":0},` +
+		`{"index":3,"existing_company_id":"8e2f4a6b-1c3d-4e5f-a7b9-c0d1e2f3a4b5"}]}`
+	assert.JSONEq(t, expectedBody, response.Body.String(), "http body must match")
+
+	// assert nothing is created
+	s.assertCompaniesCount("BATCH-DUP", 0)
+	s.assertCompaniesCount("BATCH-DUP-2", 0)
+}
+
+func (s *PostCompaniesBatchSuite) TestPostCompaniesBatch_Partial() {
+	t := s.T()
+
+	// prepare existing company
+	_, err := s.dbConn.Exec(`INSERT INTO companies (id, name, code, country, website, phone, type, created_at, updated_at)
+VALUES ('5d7a9c1e-3b2f-4e8a-9c6d-1f0e2a3b4c5d', 'TestPostCompaniesBatch_Partial', 'BATCH-PART-0', 'CY',
+        '', '', 'Corporation', now(), now())`)
+	if err != nil {
+		t.Fatalf("insert company failed: %s", err)
+	}
+
+	// prepare input data
+	inputData := strings.NewReader(`{
+	"mode": "partial",
+	"companies": [
+		{"name": "created", "code": "BATCH-PART-1", "country": "CY", "type": "Corporation"},
+		{"name": "", "code": "BATCH-PART-2", "country": "CY", "type": "Corporation"},
+		{"name": "existing", "code": "BATCH-PART-0", "country": "cy", "type": "Corporation"},
+		{"name": "duplicate", "code": "BATCH-PART-1", "country": "CY", "type": "Corporation"}
+	]
+}`)
+
+	// make request
+	response := s.makeBatchRequest(inputData)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusOK, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data []BatchItemResult `json:"data"`
+	})
+	if err = json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	if !assert.Len(t, gotBody.Data, 4, "results count must match") {
+		return
+	}
+	expectedStatuses := []int{
+		http.StatusCreated, http.StatusUnprocessableEntity, http.StatusConflict, http.StatusConflict,
+	}
+	for i, expectedStatus := range expectedStatuses {
+		assert.Equal(t, i, gotBody.Data[i].Index, "index must match")
+		assert.Equal(t, expectedStatus, gotBody.Data[i].Status, "status of company %d must match", i)
+	}
+	if assert.NotNil(t, gotBody.Data[0].Company, "created company must be returned") {
+		dbCompany := selectDbCompanyByID(t, s.dbConn, gotBody.Data[0].Company.ID.String())
+		assert.Equal(t, "created", dbCompany.Name, "db company name must match")
+	}
+	assert.Equal(t, FieldErrors{{Field: "name", Message: "is required"}}, gotBody.Data[1].Errors, "errors must match")
+	existingID := uuid.MustParse("5d7a9c1e-3b2f-4e8a-9c6d-1f0e2a3b4c5d")
+	assert.Equal(t, &existingID, gotBody.Data[2].ExistingCompanyID, "existing company must match")
+	if assert.NotNil(t, gotBody.Data[0].Company, "created company must be returned") {
+		assert.Equal(t, &gotBody.Data[0].Company.ID, gotBody.Data[3].ExistingCompanyID, "duplicated company must match")
+	}
+	s.assertCompaniesCount("BATCH-PART-1", 1)
+}
+
+func (s *PostCompaniesBatchSuite) TestPostCompaniesBatch_InvalidMode() {
+	t := s.T()
+
+	// prepare input data
+	inputData := strings.NewReader(`{
+	"mode": "best-effort",
+	"companies": [{"name": "ltd", "code": "BATCH-MODE", "country": "CY", "type": "Corporation"}]
+}`)
+
+	// make request
+	response := s.makeBatchRequest(inputData)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusBadRequest, response.Code, "http code must match")
+}
+
+func (s *PostCompaniesBatchSuite) makeBatchRequest(body io.Reader) *httptest.ResponseRecorder {
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	return makeTestRequest(s.router, http.MethodPost, "/api/v1/companies:batch", body, metadata)
+}
+
+func (s *PostCompaniesBatchSuite) assertCompaniesCount(codePattern string, expected int) {
+	t := s.T()
+	var count int
+	err := s.dbConn.Get(&count, `SELECT count(*) FROM companies WHERE code LIKE $1`, codePattern)
+	if err != nil {
+		t.Fatalf("count companies failed: %s", err)
+	}
+	assert.Equal(t, expected, count, "companies count must match")
+}
@@ -0,0 +1,354 @@
+package webapi
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+const (
+	MaxCompaniesImportRows = 10000
+	// MaxCompaniesImportSize is the limit of the CSV body in bytes.
+	MaxCompaniesImportSize = 10 << 20
+)
+
+type CompaniesImportRowStatus string
+
+const (
+	ImportRowCreated CompaniesImportRowStatus = "created"
+	// ImportRowValid is the status of rows which would be created without dry run.
+	ImportRowValid    CompaniesImportRowStatus = "valid"
+	ImportRowInvalid  CompaniesImportRowStatus = "invalid"
+	ImportRowConflict CompaniesImportRowStatus = "conflict"
+)
+
+// CompaniesImportRow is the result of the CSV row, Line is the line of the row in the file, the header is line 1.
+type CompaniesImportRow struct {
+	Line              int                      `json:"line"`
+	Status            CompaniesImportRowStatus `json:"status"`
+	CompanyID         *uuid.UUID               `json:"company_id,omitempty"`
+	ExistingCompanyID *uuid.UUID               `json:"existing_company_id,omitempty"`
+	Errors            FieldErrors              `json:"errors,omitempty"`
+}
+
+type CompaniesImportResponse struct {
+	DryRun   bool                 `json:"dry_run"`
+	Total    int                  `json:"total"`
+	Imported int                  `json:"imported"`
+	Rows     []CompaniesImportRow `json:"rows"`
+}
+
+// importColumns are the CSV columns, they are named as JSON fields of InputCompany.
+var importColumns = map[string]bool{
+	"name":            true,
+	"code":            true,
+	"country":         true,
+	"website":         true,
+	"phone":           true,
+	"description":     true,
+	"employees_count": true,
+	"registered":      true,
+	"type":            true,
+}
+
+// requiredImportColumns are the columns of required fields of InputCompany.
+var requiredImportColumns = []string{"name", "code", "country", "type"}
+
+// importRecord is the company parsed from the CSV row.
+type importRecord struct {
+	line   int
+	input  InputCompany
+	errors FieldErrors
+	// malformed row has wrong number of fields, so its values are not parsed.
+	malformed bool
+}
+
+// PostCompaniesImport creates companies from CSV with the header of InputCompany field names.
+// Companies are created by one transaction only if all rows are valid and do not conflict,
+// otherwise nothing is created and the report of every row is returned with 422.
+// With dry_run=true the rows are only checked.
+func (h *HandlerEnv) PostCompaniesImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/csv" {
+		logger.WithField("content_type", r.Header.Get("Content-Type")).Warn("unsupported content type")
+		UnsupportedMediaType(ctx, w, "content type must be text/csv")
+
+		return
+	}
+	dryRun := false
+	if rawDryRun := r.URL.Query().Get("dry_run"); rawDryRun != "" {
+		dryRun, err = strconv.ParseBool(rawDryRun)
+		if err != nil {
+			logger.WithError(err).WithField("dry_run", rawDryRun).Warn("parse dry_run failed")
+			BadRequest(ctx, w, "dry_run must be true or false")
+
+			return
+		}
+	}
+
+	records, err := readImportRecords(http.MaxBytesReader(w, r.Body, MaxCompaniesImportSize))
+	if err != nil {
+		logger.WithError(err).Warn("read csv failed")
+		BadRequest(ctx, w, err.Error())
+
+		return
+	}
+
+	response := &CompaniesImportResponse{
+		DryRun: dryRun,
+		Total:  len(records),
+		Rows:   make([]CompaniesImportRow, len(records)),
+	}
+	valid, err := checkImportRecords(ctx, dbConn, records, response.Rows)
+	if err != nil {
+		logger.WithError(err).Error("check companies failed")
+		InternalServerError(ctx, w, "import companies failed")
+
+		return
+	}
+	if !valid {
+		logger.Warn("invalid companies import")
+		UnprocessableEntity(ctx, w, "invalid companies", response)
+
+		return
+	}
+	if dryRun {
+		OKResponse(ctx, w, response)
+
+		return
+	}
+
+	inputs := make([]InputCompany, 0, len(records))
+	for i := range records {
+		inputs = append(inputs, records[i].input)
+	}
+	dbCompanies := newBatchDbCompanies(inputs, authn.Actor(ctx))
+	err = db.WithTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+		for start := 0; start < len(dbCompanies); start += MaxCompaniesBatchSize {
+			end := start + MaxCompaniesBatchSize
+			if end > len(dbCompanies) {
+				end = len(dbCompanies)
+			}
+			chunk := dbCompanies[start:end]
+			if createErr := db.CreateCompanies(ctx, tx, chunk); createErr != nil {
+				return createErr
+			}
+			changes := make([]companyChange, 0, len(chunk))
+			for i := range chunk {
+				changes = append(changes, companyChange{After: &chunk[i]})
+			}
+			if recordErr := recordCompanyChanges(ctx, tx, db.CompanyAuditCreate, changes...); recordErr != nil {
+				return recordErr
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.WithError(err).Error("import companies failed")
+		if !companyConflict(ctx, w, dbConn, err) {
+			InternalServerError(ctx, w, "import companies failed")
+		}
+
+		return
+	}
+
+	for i := range dbCompanies {
+		response.Rows[i].Status = ImportRowCreated
+		response.Rows[i].CompanyID = &dbCompanies[i].ID
+	}
+	response.Imported = len(dbCompanies)
+	CreatedResponse(ctx, w, response)
+}
+
+// readImportRecords parses the header and all rows, errors of values are reported per row.
+func readImportRecords(body io.Reader) ([]importRecord, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("csv must contain the header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read csv header failed: %w", err)
+	}
+	columns, err := parseImportHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]importRecord, 0)
+	for {
+		row, readErr := reader.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		// rows with wrong number of fields are reported, other errors break the format
+		if readErr != nil && !errors.Is(readErr, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("read csv failed: %w", readErr)
+		}
+		if len(records) == MaxCompaniesImportRows {
+			return nil, fmt.Errorf("csv must contain at most %d rows", MaxCompaniesImportRows)
+		}
+		line, _ := reader.FieldPos(0)
+		record := importRecord{line: line, errors: make(FieldErrors, 0)}
+		if readErr != nil {
+			record.errors.add("row", fmt.Sprintf("must have %d fields", len(columns)))
+			record.malformed = true
+			records = append(records, record)
+
+			continue
+		}
+		for i, column := range columns {
+			if message := setImportValue(&record.input, column, strings.TrimSpace(row[i])); message != "" {
+				record.errors.add(column, message)
+			}
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("csv must contain at least one row")
+	}
+
+	return records, nil
+}
+
+// setImportValue sets the field of the column, the message is returned if the value can not be converted.
+func setImportValue(input *InputCompany, column, value string) string {
+	switch column {
+	case "name":
+		input.Name = value
+	case "code":
+		input.Code = value
+	case "country":
+		input.Country = value
+	case "website":
+		input.WebSite = value
+	case "phone":
+		input.Phone = value
+	case "description":
+		input.Description = value
+	case "type":
+		input.Type = db.CompanyType(value)
+	case "employees_count":
+		if value == "" {
+			return ""
+		}
+		employeesCount, err := strconv.ParseInt(value, 10, 32)
+		switch {
+		case errors.Is(err, strconv.ErrRange) && strings.HasPrefix(value, "-"):
+			return "must not be negative"
+		case errors.Is(err, strconv.ErrRange):
+			return fmt.Sprintf("must be at most %d", MaxEmployeesCount)
+		case err != nil:
+			return "must be integer"
+		}
+		input.EmployeesCount = int(employeesCount)
+	case "registered":
+		if value == "" {
+			return ""
+		}
+		registered, err := strconv.ParseBool(value)
+		if err != nil {
+			return "must be true or false"
+		}
+		input.Registered = registered
+	}
+
+	return ""
+}
+
+func parseImportHeader(header []string) ([]string, error) {
+	columns := make([]string, 0, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, rawColumn := range header {
+		if i == 0 {
+			// spreadsheets may start the file with UTF-8 BOM
+			rawColumn = strings.TrimPrefix(rawColumn, "\ufeff")
+		}
+		column := strings.ToLower(strings.TrimSpace(rawColumn))
+		if !importColumns[column] {
+			return nil, fmt.Errorf("unknown csv column %q", rawColumn)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("duplicate csv column %q", rawColumn)
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+	for _, column := range requiredImportColumns {
+		if !seen[column] {
+			return nil, fmt.Errorf("csv column %q is required", column)
+		}
+	}
+
+	return columns, nil
+}
+
+// checkImportRecords validates records, finds duplicates within the file and conflicts with existing companies,
+// the result of every record is put into rows. It returns true if all records can be created.
+func checkImportRecords(
+	ctx context.Context, dbConn sqlx.QueryerContext, records []importRecord, rows []CompaniesImportRow,
+) (bool, error) {
+	valid := true
+	codes := make([]db.CompanyCode, 0, len(records))
+	codeLines := make(map[db.CompanyCode]int, len(records))
+	for i := range records {
+		record := &records[i]
+		rows[i].Line = record.line
+		if !record.malformed {
+			record.errors = append(record.errors, record.input.Validate()...)
+		}
+		if len(record.errors) == 0 {
+			code := companyCode(record.input.Code, record.input.Country)
+			if firstLine, ok := codeLines[code]; ok {
+				record.errors.add("code", fmt.Sprintf("duplicates code and country of line %d", firstLine))
+			} else {
+				codeLines[code] = record.line
+				codes = append(codes, code)
+			}
+		}
+		if len(record.errors) > 0 {
+			valid = false
+			rows[i].Status = ImportRowInvalid
+			rows[i].Errors = record.errors
+
+			continue
+		}
+		rows[i].Status = ImportRowValid
+	}
+
+	existingIDs, err := existingCompanyIDs(ctx, dbConn, codes)
+	if err != nil {
+		return false, err
+	}
+	for i := range records {
+		if rows[i].Status != ImportRowValid {
+			continue
+		}
+		if existingID, ok := existingIDs[companyCode(records[i].input.Code, records[i].input.Country)]; ok {
+			valid = false
+			rows[i].Status = ImportRowConflict
+			rows[i].ExistingCompanyID = &existingID
+		}
+	}
+
+	return valid, nil
+}
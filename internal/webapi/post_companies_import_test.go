@@ -0,0 +1,235 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type PostCompaniesImportSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestPostCompaniesImportSuite(t *testing.T) {
+	s := new(PostCompaniesImportSuite)
+	suite.Run(t, s)
+}
+
+func (s *PostCompaniesImportSuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil)
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("import-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *PostCompaniesImportSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *PostCompaniesImportSuite) TestPostCompaniesImport_OK() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"Content-Type":  "text/csv; charset=utf-8",
+		},
+	}
+	body := "name,code,country,type,employees_count,registered,website\n" +
+		"ltd,IMPORT1,CY,Corporation,10,true,https://example.com\n" +
+		"\"Ltd, Inc.\",IMPORT2,md,NonProfit,,,\n"
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/import", strings.NewReader(body), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusCreated, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data CompaniesImportResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	assert.Equal(t, 2, gotBody.Data.Total, "total must match")
+	assert.Equal(t, 2, gotBody.Data.Imported, "imported must match")
+	if !assert.Len(t, gotBody.Data.Rows, 2, "rows count must match") {
+		return
+	}
+
+	// assert DB
+	for i, expected := range []struct {
+		line int
+		name string
+	}{{line: 2, name: "ltd"}, {line: 3, name: "Ltd, Inc."}} {
+		row := gotBody.Data.Rows[i]
+		assert.Equal(t, expected.line, row.Line, "line must match")
+		assert.Equal(t, ImportRowCreated, row.Status, "status must match")
+		if !assert.NotNil(t, row.CompanyID, "company id must be returned") {
+			continue
+		}
+		dbCompany := selectDbCompanyByID(t, s.dbConn, row.CompanyID.String())
+		assert.Equal(t, expected.name, dbCompany.Name, "name must match")
+	}
+}
+
+func (s *PostCompaniesImportSuite) TestPostCompaniesImport_Invalid() {
+	t := s.T()
+	existingCompanyID := "6f3a4b50-8c9d-4e0f-b1a2-c3d4e5f60718"
+	fakePatch := gomonkey.ApplyFunc(NewCompanyID, func() uuid.UUID {
+		return uuid.MustParse(existingCompanyID)
+	})
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"Content-Type":  "text/csv",
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/import",
+		strings.NewReader("name,code,country,type\nltd,EXISTING,CY,Corporation\n"), metadata)
+	fakePatch.Reset()
+	assert.Equal(t, http.StatusCreated, response.Code, "http code of the first import must match")
+
+	// make request
+	body := "name,code,country,type,employees_count\n" +
+		"ltd,NEW,CY,Corporation,1\n" +
+		"ltd,BAD,CY,Unknown,many\n" +
+		"ltd,NEW,cy,Corporation,2\n" +
+		"ltd,EXISTING,cy,Corporation,3\n" +
+		"ltd,SHORT\n"
+	response = makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/import?dry_run=true",
+		strings.NewReader(body), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code must match")
+
+	// assert HTTP body
+	expectedBody := `{
+	"error": "invalid companies",
+	"details": {
+		"dry_run": true,
+		"total": 5,
+		"imported": 0,
+		"rows": [
+			{"line": 2, "status": "valid"},
+			{"line": 3, "status": "invalid", "errors": [
+				{"field": "employees_count", "message": "must be integer"},
+				{"field": "type", "message": "must be one of Corporation, NonProfit, Cooperative, Sole Proprietorship"}
+			]},
+			{"line": 4, "status": "invalid", "errors": [
+				{"field": "code", "message": "duplicates code and country of line 2"}
+			]},
+			{"line": 5, "status": "conflict", "existing_company_id": "` + existingCompanyID + `"},
+			{"line": 6, "status": "invalid", "errors": [{"field": "row", "message": "must have 5 fields"}]}
+		]
+	}
+}`
+	assert.JSONEq(t, expectedBody, response.Body.String(), "http body must match")
+}
+
+func (s *PostCompaniesImportSuite) TestPostCompaniesImport_UnsupportedMediaType() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+			"Content-Type":  "application/json",
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies/import",
+		strings.NewReader(`{"name": "ltd"}`), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusUnsupportedMediaType, response.Code, "http code must match")
+}
+
+func TestReadImportRecords_InvalidHeader(t *testing.T) {
+	testCases := []struct {
+		body        string
+		expectedErr string
+	}{
+		{body: "", expectedErr: "csv must contain the header"},
+		{body: "name,code,country,type\n", expectedErr: "csv must contain at least one row"},
+		{body: "name,code,country,type,owner\n", expectedErr: `unknown csv column "owner"`},
+		{body: "name,code,country,type,Name\n", expectedErr: `duplicate csv column "Name"`},
+		{body: "name,code,country\n", expectedErr: `csv column "type" is required`},
+	}
+	for _, tc := range testCases {
+		_, err := readImportRecords(strings.NewReader(tc.body))
+		assert.EqualError(t, err, tc.expectedErr, "error of %q must match", tc.body)
+	}
+}
+
+func TestReadImportRecords_BOM(t *testing.T) {
+	records, err := readImportRecords(strings.NewReader("\ufeffName, Code ,country,type\nltd,007,CY,Corporation\n"))
+	if err != nil {
+		t.Fatalf("read records failed: %s", err)
+	}
+
+	expectedInput := InputCompany{Name: "ltd", Code: "007", Country: "CY", Type: db.CompanyTypeCorporation}
+	if assert.Len(t, records, 1, "records count must match") {
+		assert.Equal(t, expectedInput, records[0].input, "company must match")
+		assert.Empty(t, records[0].errors, "errors must be empty")
+	}
+}
+
+func TestReadImportRecords_EmployeesCountOutOfRange(t *testing.T) {
+	body := "name,code,country,type,employees_count\n" +
+		"ltd,001,CY,Corporation,2147483648\n" +
+		"ltd,002,CY,Corporation,-9999999999\n" +
+		"ltd,003,CY,Corporation,many\n"
+	records, err := readImportRecords(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("read records failed: %s", err)
+	}
+
+	expectedErrors := []FieldErrors{
+		{{Field: "employees_count", Message: "must be at most 2147483647"}},
+		{{Field: "employees_count", Message: "must not be negative"}},
+		{{Field: "employees_count", Message: "must be integer"}},
+	}
+	if assert.Len(t, records, len(expectedErrors), "records count must match") {
+		for i, expected := range expectedErrors {
+			assert.Equal(t, expected, records[i].errors, "errors of record %d must match", i)
+		}
+	}
+}
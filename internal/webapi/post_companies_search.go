@@ -1,17 +1,28 @@
 package webapi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/pzabolotniy/logging/pkg/logging"
 
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
 	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
 )
 
 type CompaniesSearchRequest struct {
 	CompaniesIDs []string `json:"companies_ids"` //nolint:tagliatelle // false positive
+	// Query is free text to search by name and code, it can not be combined with CompaniesIDs.
+	Query string `json:"query"`
+	// Limit is the max number of companies found by Query.
+	Limit          int  `json:"limit"`
+	IncludeDeleted bool `json:"include_deleted"`
+	// Strict search fails if any of CompaniesIDs is invalid or not found, it can not be combined with Query.
+	Strict bool `json:"strict"`
 }
 
 type CompaniesSearchResponse []CompanyResponse
@@ -23,7 +34,6 @@ type CompaniesSearchResponse []CompanyResponse
 func (h *HandlerEnv) PostCompaniesSearch(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	logger := logging.FromContext(ctx)
-	dbConn := h.DbConn
 
 	input := new(CompaniesSearchRequest)
 	err := json.NewDecoder(r.Body).Decode(input)
@@ -34,18 +44,50 @@ func (h *HandlerEnv) PostCompaniesSearch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !canSeeDeleted(ctx, w, input.IncludeDeleted) {
+		return
+	}
+	if input.Query != "" {
+		h.searchCompaniesByText(ctx, w, input)
+
+		return
+	}
+
+	h.searchCompaniesByIDs(ctx, w, input)
+}
+
+// searchCompaniesByIDs returns companies with the given IDs,
+// IDs which are not valid UUIDs and IDs of not found companies are reported separately.
+func (h *HandlerEnv) searchCompaniesByIDs(ctx context.Context, w http.ResponseWriter, input *CompaniesSearchRequest) {
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	if maxIDs := searchMaxIDs(h.SearchConf); maxIDs > 0 && len(input.CompaniesIDs) > maxIDs {
+		logger.WithField("companies_ids_count", len(input.CompaniesIDs)).Warn("too many companies_ids")
+		BadRequest(ctx, w, fmt.Sprintf("too many companies_ids, max is %d", maxIDs))
+
+		return
+	}
+
 	companyIDs := make([]uuid.UUID, 0)
+	invalidIDs := make([]string, 0)
 	for _, inputID := range input.CompaniesIDs {
 		companyID, parseErr := uuid.Parse(inputID)
 		if parseErr != nil {
 			logger.WithError(parseErr).WithField("company_id", inputID).Warn("parse companyID failed")
+			invalidIDs = append(invalidIDs, inputID)
 
 			continue
 		}
 		companyIDs = append(companyIDs, companyID)
 	}
+	if input.Strict && len(invalidIDs) > 0 {
+		searchFailedResponse(ctx, w, "invalid companies_ids", invalidIDs, nil)
 
-	dbCompanies, err := db.GetCompaniesListByID(ctx, dbConn, companyIDs)
+		return
+	}
+
+	dbCompanies, err := db.GetCompaniesListByID(ctx, dbConn, companyIDs, input.IncludeDeleted)
 	if err != nil {
 		logger.WithError(err).Error("select companies failed")
 		BadRequest(ctx, w, "select companies failed")
@@ -53,21 +95,112 @@ func (h *HandlerEnv) PostCompaniesSearch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	notFound := notFoundCompanyIDs(companyIDs, dbCompanies)
+	if input.Strict && len(notFound) > 0 {
+		searchFailedResponse(ctx, w, "companies not found", nil, notFound)
+
+		return
+	}
+
 	response := make(CompaniesSearchResponse, 0)
 	for i := range dbCompanies {
-		dbCompany := dbCompanies[i]
-		companyResponse := CompanyResponse{
-			CreatedAt: dbCompany.CreatedAt,
-			InputCompany: InputCompany{
-				Name:    dbCompany.Name,
-				Code:    dbCompany.Code,
-				Country: dbCompany.Country,
-				WebSite: dbCompany.WebSite,
-				Phone:   dbCompany.Phone,
-			},
-			ID: dbCompany.ID,
-		}
+		companyResponse := newCompanyResponse(&dbCompanies[i])
 		response = append(response, companyResponse)
 	}
+	respBody := &ResponseBody{
+		Data:       response,
+		InvalidIDs: invalidIDs,
+		NotFound:   notFound,
+	}
+	makeJSONResponse(ctx, w, &Response{HTTPStatus: http.StatusOK, HTTPBody: respBody})
+}
+
+// notFoundCompanyIDs returns requested IDs missing in the found companies, each ID is reported once.
+func notFoundCompanyIDs(companyIDs []uuid.UUID, dbCompanies []db.Company) []uuid.UUID {
+	found := make(map[uuid.UUID]bool, len(dbCompanies))
+	for i := range dbCompanies {
+		found[dbCompanies[i].ID] = true
+	}
+	notFound := make([]uuid.UUID, 0)
+	for _, companyID := range companyIDs {
+		if !found[companyID] {
+			notFound = append(notFound, companyID)
+			found[companyID] = true
+		}
+	}
+
+	return notFound
+}
+
+func searchFailedResponse(ctx context.Context, w http.ResponseWriter, msg string, invalidIDs []string, notFound []uuid.UUID) {
+	logger := logging.FromContext(ctx)
+	logger.
+		WithFields(logging.Fields{
+			"invalid_ids": invalidIDs,
+			"not_found":   notFound,
+		}).
+		Warn("strict search failed")
+	respBody := &ResponseBody{
+		Error:      msg,
+		InvalidIDs: invalidIDs,
+		NotFound:   notFound,
+	}
+	makeJSONResponse(ctx, w, &Response{HTTPStatus: http.StatusBadRequest, HTTPBody: respBody})
+}
+
+// searchCompaniesByText returns companies ranked by relevance to the query.
+func (h *HandlerEnv) searchCompaniesByText(ctx context.Context, w http.ResponseWriter, input *CompaniesSearchRequest) {
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	if len(input.CompaniesIDs) > 0 {
+		logger.Warn("both companies_ids and query passed")
+		BadRequest(ctx, w, "companies_ids and query can not be combined")
+
+		return
+	}
+	if input.Strict {
+		logger.Warn("strict passed with query")
+		BadRequest(ctx, w, "strict can not be combined with query")
+
+		return
+	}
+	limit := input.Limit
+	if limit == 0 {
+		limit = DefaultCompaniesPageSize
+	}
+	if limit < 1 || limit > MaxCompaniesPageSize {
+		logger.WithField("limit", limit).Warn("invalid limit")
+		BadRequest(ctx, w, fmt.Sprintf("limit must be from 1 to %d", MaxCompaniesPageSize))
+
+		return
+	}
+
+	dbCompanies, err := db.SearchCompanies(ctx, dbConn, input.Query, limit, input.IncludeDeleted)
+	if err != nil {
+		logger.WithError(err).Error("search companies failed")
+		if errors.Is(err, db.ErrEmptySearchQuery) {
+			BadRequest(ctx, w, "query must contain letters or digits")
+
+			return
+		}
+		InternalServerError(ctx, w, "search companies failed")
+
+		return
+	}
+
+	response := make(CompaniesSearchResponse, 0, len(dbCompanies))
+	for i := range dbCompanies {
+		response = append(response, newCompanyResponse(&dbCompanies[i]))
+	}
 	OKResponse(ctx, w, response)
 }
+
+// searchMaxIDs returns the max number of companies_ids of one request, 0 means no limit.
+func searchMaxIDs(searchConf *config.Search) int {
+	if searchConf == nil {
+		return 0
+	}
+
+	return searchConf.MaxIDs
+}
@@ -108,7 +108,7 @@ func (s *PostCompaniesSearchSuite) TearDownSuite() {
 }
 
 func (s *PostCompaniesSearchSuite) SetupTest() {
-	handler := &HandlerEnv{DbConn: s.dbConn}
+	handler := &HandlerEnv{DbConn: s.dbConn, SearchConf: &config.Search{MaxIDs: 3}}
 	routerParams := &RouterParams{
 		Logger:    s.logger,
 		Handler:   handler,
@@ -151,7 +151,16 @@ func (s *PostCompaniesSearchSuite) TestPostCompaniesSearch_OK() {
 			"country": "Moon",
 			"website": "Moon.dark",
 			"phone": "+65748329",
-			"created_at": "2022-09-16T16:05:15Z"
+			"description": "",
+			"employees_count": 0,
+			"registered": false,
+			"type": "Corporation",
+			"created_at": "2022-09-16T16:05:15Z",
+			"updated_at": "2022-09-16T16:05:15Z",
+			"version": 1,
+			"tags": [],
+			"created_by": "",
+			"updated_by": ""
 		},
 		{
 			"id": "5b6e7620-808f-4c9a-887c-56fe5290f535",
@@ -160,9 +169,244 @@ func (s *PostCompaniesSearchSuite) TestPostCompaniesSearch_OK() {
 			"country": "Sun",
 			"website": "sun.info",
 			"phone": "+987765543",
-			"created_at": "2022-09-16T07:36:15Z"
+			"description": "",
+			"employees_count": 0,
+			"registered": false,
+			"type": "Corporation",
+			"created_at": "2022-09-16T07:36:15Z",
+			"updated_at": "2022-09-16T07:36:15Z",
+			"version": 1,
+			"tags": [],
+			"created_by": "",
+			"updated_by": ""
 		}
 	]
 }`
 	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
 }
+
+func (s *PostCompaniesSearchSuite) TestPostCompaniesSearch_Query() {
+	t := s.T()
+
+	// testdata: misspelled name
+	body := strings.NewReader(`{"query": "TestGetCompani"}`)
+
+	// make request
+	testURL := "/api/v1/search/companies"
+	response := makeTestRequest(s.router, http.MethodPost, testURL, body, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"data": [
+		{
+			"id": "43fa9b5e-87bf-45d1-ad3a-b15df0037f37",
+			"name": "TestGetCompany_OK",
+			"code": "OK",
+			"country": "Moon",
+			"website": "Moon.dark",
+			"phone": "+65748329",
+			"description": "",
+			"employees_count": 0,
+			"registered": false,
+			"type": "Corporation",
+			"created_at": "2022-09-16T16:05:15Z",
+			"updated_at": "2022-09-16T16:05:15Z",
+			"version": 1,
+			"tags": [],
+			"created_by": "",
+			"updated_by": ""
+		}
+	]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PostCompaniesSearchSuite) TestPostCompaniesSearch_QueryWithIDs() {
+	t := s.T()
+
+	// testdata
+	body := strings.NewReader(`{"query": "ok", "companies_ids": ["43fa9b5e-87bf-45d1-ad3a-b15df0037f37"]}`)
+
+	// make request
+	testURL := "/api/v1/search/companies"
+	response := makeTestRequest(s.router, http.MethodPost, testURL, body, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusBadRequest
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "companies_ids and query can not be combined"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PostCompaniesSearchSuite) TestPostCompaniesSearch_QueryStrict() {
+	t := s.T()
+
+	// testdata
+	body := strings.NewReader(`{"query": "ok", "strict": true}`)
+
+	// make request
+	testURL := "/api/v1/search/companies"
+	response := makeTestRequest(s.router, http.MethodPost, testURL, body, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusBadRequest
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "strict can not be combined with query"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PostCompaniesSearchSuite) TestPostCompaniesSearch_InvalidAndNotFoundIDs() {
+	t := s.T()
+
+	// testdata
+	companyID := "43fa9b5e-87bf-45d1-ad3a-b15df0037f37"
+	notFoundID := "00000000-0000-4000-8000-000000000001"
+	body := strings.NewReader(fmt.Sprintf(`{"companies_ids": ["%s", "not-a-uuid", "%s"]}`, companyID, notFoundID))
+
+	// make request
+	testURL := "/api/v1/search/companies"
+	response := makeTestRequest(s.router, http.MethodPost, testURL, body, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"data": [
+		{
+			"id": "43fa9b5e-87bf-45d1-ad3a-b15df0037f37",
+			"name": "TestGetCompany_OK",
+			"code": "OK",
+			"country": "Moon",
+			"website": "Moon.dark",
+			"phone": "+65748329",
+			"description": "",
+			"employees_count": 0,
+			"registered": false,
+			"type": "Corporation",
+			"created_at": "2022-09-16T16:05:15Z",
+			"updated_at": "2022-09-16T16:05:15Z",
+			"version": 1,
+			"tags": [],
+			"created_by": "",
+			"updated_by": ""
+		}
+	],
+	"invalid_ids": ["not-a-uuid"],
+	"not_found": ["00000000-0000-4000-8000-000000000001"]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PostCompaniesSearchSuite) TestPostCompaniesSearch_StrictNotFound() {
+	t := s.T()
+
+	// testdata
+	companyID := "43fa9b5e-87bf-45d1-ad3a-b15df0037f37"
+	notFoundID := "00000000-0000-4000-8000-000000000001"
+	body := strings.NewReader(fmt.Sprintf(`{"companies_ids": ["%s", "%s"], "strict": true}`, companyID, notFoundID))
+
+	// make request
+	testURL := "/api/v1/search/companies"
+	response := makeTestRequest(s.router, http.MethodPost, testURL, body, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusBadRequest
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "companies not found",
+	"not_found": ["00000000-0000-4000-8000-000000000001"]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PostCompaniesSearchSuite) TestPostCompaniesSearch_StrictInvalidID() {
+	t := s.T()
+
+	// testdata
+	body := strings.NewReader(`{"companies_ids": ["not-a-uuid"], "strict": true}`)
+
+	// make request
+	testURL := "/api/v1/search/companies"
+	response := makeTestRequest(s.router, http.MethodPost, testURL, body, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusBadRequest
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "invalid companies_ids",
+	"invalid_ids": ["not-a-uuid"]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PostCompaniesSearchSuite) TestPostCompaniesSearch_TooManyIDs() {
+	t := s.T()
+
+	// testdata: more IDs than SearchConf.MaxIDs
+	body := strings.NewReader(`{"companies_ids": ["1", "2", "3", "4"]}`)
+
+	// make request
+	testURL := "/api/v1/search/companies"
+	response := makeTestRequest(s.router, http.MethodPost, testURL, body, nil)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusBadRequest
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{"error": "too many companies_ids, max is 3"}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
@@ -111,7 +111,7 @@ func (s *PostCompaniesSuite) SetupTest() {
 
 	handler := &HandlerEnv{DbConn: s.dbConn}
 	tokenService := authn.NewTokenService(s.appConf.ClientToken)
-	testJWT, err := tokenService.IssueToken()
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("companies-tester"))
 	if err != nil {
 		s.T().Fatal(err)
 	}
@@ -155,7 +155,11 @@ func (s *PostCompaniesSuite) TestPostCompanies_OK() {
 	"code": "007",
 	"country": "md",
 	"website": "http://google.com",
-	"phone": "+995987655443"
+	"phone": "+995987655443",
+	"description": "Search engine",
+	"employees_count": 100,
+	"registered": true,
+	"type": "Cooperative"
 }`)
 
 	// make request
@@ -171,6 +175,7 @@ func (s *PostCompaniesSuite) TestPostCompanies_OK() {
 	gotHTTPCode := response.Code
 	expectedHTTPCode := http.StatusCreated
 	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+	assert.Equal(t, `"1"`, response.Header().Get("ETag"), "etag must match")
 
 	// assert HTTP body
 	gotBody, err := io.ReadAll(response.Body)
@@ -182,12 +187,21 @@ func (s *PostCompaniesSuite) TestPostCompanies_OK() {
 		"id": "%s",
 		"name": "ltd",
 		"code": "007",
-		"country": "md",
+		"country": "MD",
 		"website": "http://google.com",
 		"phone": "+995987655443",
-		"created_at": "%s"
+		"description": "Search engine",
+		"employees_count": 100,
+		"registered": true,
+		"type": "Cooperative",
+		"created_at": "%s",
+		"updated_at": "%s",
+		"version": 1,
+		"tags": [],
+		"created_by": "test/companies-tester",
+		"updated_by": "test/companies-tester"
 	}
-}`, fakeUUID, fakeTime.Format(time.RFC3339))
+}`, fakeUUID, fakeTime.Format(time.RFC3339), fakeTime.Format(time.RFC3339))
 	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
 
 	// assert db values
@@ -195,13 +209,263 @@ func (s *PostCompaniesSuite) TestPostCompanies_OK() {
 	expectedDbCompany := &db.Company{
 		Name:      "ltd",
 		Code:      "007",
-		Country:   "md",
+		Country:   "MD",
 		WebSite:   "http://google.com",
 		CreatedAt: fakeTime,
+		UpdatedAt: fakeTime,
 		Phone:     "+995987655443",
 		ID:        fakeUUID,
+		Version:   1,
+
+		Description:    "Search engine",
+		EmployeesCount: 100,
+		Registered:     true,
+		Type:           db.CompanyTypeCooperative,
 	}
 	assert.Equal(t, expectedDbCompany, dbCompany, "db company must match")
+
+	// assert outbox event
+	var eventTypes []string
+	err = s.dbConn.Select(&eventTypes, `SELECT type FROM company_outbox WHERE company_id = $1`, fakeUUID)
+	if err != nil {
+		t.Fatalf("select company events failed: %s", err)
+	}
+	assert.Equal(t, []string{string(db.CompanyCreated)}, eventTypes, "company events must match")
+}
+
+func (s *PostCompaniesSuite) TestPostCompanies_ValidationFailed() {
+	t := s.T()
+
+	// prepare input data
+	inputData := strings.NewReader(`{
+	"name": "",
+	"code": "01234567890123456789",
+	"country": "Moon",
+	"website": "google.com",
+	"phone": "call me",
+	"employees_count": -1,
+	"type": "LLC"
+}`)
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies", inputData, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	expectedHTTPCode := http.StatusUnprocessableEntity
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPBody := `{
+	"error": "invalid company",
+	"details": [
+		{"field": "name", "message": "is required"},
+		{"field": "code", "message": "must be at most 16 characters"},
+		{"field": "country", "message": "must be ISO 3166-1 alpha-2 code"},
+		{"field": "website", "message": "must be absolute http(s) URL"},
+		{"field": "phone", "message": "must be phone number of 4-15 digits"},
+		{"field": "employees_count", "message": "must not be negative"},
+		{"field": "type", "message": "must be one of Corporation, NonProfit, Cooperative, Sole Proprietorship"}
+	]
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PostCompaniesSuite) TestPostCompanies_Conflict() {
+	t := s.T()
+
+	// prepare existing company
+	existingCompanyID := "b4b1c2de-2f7c-4c35-9f0e-5a6c1d7e8f90"
+	_, err := s.dbConn.Exec(`INSERT INTO companies (id, name, code, country, website, phone, type, created_at, updated_at)
+VALUES ($1, 'TestPostCompanies_Conflict', 'DUP', 'CY', '', '', 'Corporation', now(), now())`, existingCompanyID)
+	if err != nil {
+		t.Fatalf("insert company failed: %s", err)
+	}
+
+	// prepare input data
+	inputData := strings.NewReader(`{
+	"name": "ltd",
+	"code": "DUP",
+	"country": "cy",
+	"type": "Corporation"
+}`)
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies", inputData, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	expectedHTTPCode := http.StatusConflict
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPBody := fmt.Sprintf(`{
+	"error": "company with the same code and country already exists",
+	"details": {
+		"existing_company_id": "%s"
+	}
+}`, existingCompanyID)
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PostCompaniesSuite) TestPostCompanies_ConcurrentConflict() {
+	t := s.T()
+	ctx := logging.WithContext(context.Background(), s.logger)
+
+	// prepare the company written concurrently, its unique violation carries no ID
+	existingCompanyID := "0c5e7a91-8d2b-4f36-b1a4-7e9d3c2f6a58"
+	_, err := s.dbConn.Exec(`INSERT INTO companies (id, name, code, country, website, phone, type, created_at, updated_at)
+VALUES ($1, 'TestPostCompanies_ConcurrentConflict', 'RACE', 'CY', '', '', 'Corporation', now(), now())`,
+		existingCompanyID)
+	if err != nil {
+		t.Fatalf("insert company failed: %s", err)
+	}
+	conflictErr := &db.CompanyConflictError{Company: &db.Company{ID: uuid.New(), Code: "RACE", Country: "cy"}}
+
+	// respond
+	response := httptest.NewRecorder()
+	responded := companyConflict(ctx, response, s.dbConn, fmt.Errorf("create company failed: %w", conflictErr))
+
+	// assert the conflicting company is looked up
+	assert.True(t, responded, "conflict must be responded")
+	assert.Equal(t, http.StatusConflict, response.Code, "http code must match")
+	expectedHTTPBody := fmt.Sprintf(`{
+	"error": "company with the same code and country already exists",
+	"details": {
+		"existing_company_id": "%s"
+	}
+}`, existingCompanyID)
+	assert.JSONEq(t, expectedHTTPBody, response.Body.String(), "body must match")
+}
+
+func (s *PostCompaniesSuite) TestPostCompanies_IdempotentRetry() {
+	t := s.T()
+
+	// make the first request and the retry with the same key, formatting of the body does not matter
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization":      fmt.Sprintf("Bearer %s", s.testJWT),
+			IdempotencyKeyHeader: "TestPostCompanies_IdempotentRetry",
+		},
+	}
+	firstResponse := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "IDEMPOTENT", "country": "CY", "type": "Corporation"}`), metadata)
+	retryResponse := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"type":"Corporation","country":"CY","code":"IDEMPOTENT","name":"ltd"}`), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusCreated, firstResponse.Code, "http code must match")
+	assert.Equal(t, http.StatusCreated, retryResponse.Code, "http code of retry must match")
+	assert.Equal(t, `"1"`, retryResponse.Header().Get("ETag"), "etag of retry must match")
+
+	// assert HTTP body
+	firstBody, err := io.ReadAll(firstResponse.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	retryBody, err := io.ReadAll(retryResponse.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	assert.Equal(t, string(firstBody), string(retryBody), "body of retry must match")
+
+	// assert only one company is created
+	var count int
+	err = s.dbConn.Get(&count, `SELECT count(*) FROM companies WHERE code = 'IDEMPOTENT'`)
+	if err != nil {
+		t.Fatalf("count companies failed: %s", err)
+	}
+	assert.Equal(t, 1, count, "companies count must match")
+}
+
+func (s *PostCompaniesSuite) TestPostCompanies_IdempotencyKeyReused() {
+	t := s.T()
+
+	// make the first request and another request with the same key
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization":      fmt.Sprintf("Bearer %s", s.testJWT),
+			IdempotencyKeyHeader: "TestPostCompanies_IdempotencyKeyReused",
+		},
+	}
+	firstResponse := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "REUSED-1", "country": "CY", "type": "Corporation"}`), metadata)
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "REUSED-2", "country": "CY", "type": "Corporation"}`), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusCreated, firstResponse.Code, "http code must match")
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code of reused key must match")
+
+	// assert HTTP body
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPBody := `{"error": "idempotency key was used with another request"}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
+
+func (s *PostCompaniesSuite) TestPostCompanies_IdempotencyKeyOfAnotherClient() {
+	t := s.T()
+	anotherJWT, err := authn.NewTokenService(s.appConf.ClientToken).IssueToken(authn.WithSubject("another-client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// make requests of two clients of one tenant with the same key
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization":      fmt.Sprintf("Bearer %s", s.testJWT),
+			IdempotencyKeyHeader: "TestPostCompanies_IdempotencyKeyOfAnotherClient",
+		},
+	}
+	anotherMetadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization":      fmt.Sprintf("Bearer %s", anotherJWT),
+			IdempotencyKeyHeader: "TestPostCompanies_IdempotencyKeyOfAnotherClient",
+		},
+	}
+	firstResponse := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "CLIENT-1", "country": "CY", "type": "Corporation"}`), metadata)
+	anotherResponse := makeTestRequest(s.router, http.MethodPost, "/api/v1/companies",
+		strings.NewReader(`{"name": "ltd", "code": "CLIENT-2", "country": "CY", "type": "Corporation"}`), anotherMetadata)
+
+	// assert HTTP code, the response of the first client is not replayed to another one
+	assert.Equal(t, http.StatusCreated, firstResponse.Code, "http code must match")
+	assert.Equal(t, http.StatusCreated, anotherResponse.Code, "http code of another client must match")
+
+	// assert both companies are created
+	var count int
+	err = s.dbConn.Get(&count, `SELECT count(*) FROM companies WHERE code IN ('CLIENT-1', 'CLIENT-2')`)
+	if err != nil {
+		t.Fatalf("count companies failed: %s", err)
+	}
+	assert.Equal(t, 2, count, "companies count must match")
 }
 
 func postgresqlResource(ctx context.Context, t *testing.T, pool *dockertest.Pool, dbUser, dbName, sslMode string) (*dockertest.Resource, *sqlx.DB, *config.DB) {
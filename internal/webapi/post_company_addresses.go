@@ -0,0 +1,62 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// PostCompanyAddresses adds the address to the company, the company has at most one registered address.
+func (h *HandlerEnv) PostCompanyAddresses(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return
+	}
+	input := new(InputAddress)
+	if err = json.NewDecoder(r.Body).Decode(input); err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+	if validationErrs := input.Validate(); len(validationErrs) > 0 {
+		logger.WithField("validation_errors", validationErrs).Warn("invalid address")
+		UnprocessableEntity(ctx, w, "invalid address", validationErrs)
+
+		return
+	}
+
+	createdAt := NewCreatedAt()
+	address := &db.CompanyAddress{
+		ID:        NewAddressID(),
+		CompanyID: companyID,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	input.dbAddress(address)
+	err = withCompanyLocked(ctx, dbConn, companyID, func(tx *sqlx.Tx) error {
+		return db.CreateCompanyAddress(ctx, tx, address)
+	})
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("create address failed")
+		addressWriteFailed(ctx, w, err)
+
+		return
+	}
+
+	CreatedResponse(ctx, w, newAddressResponse(address))
+}
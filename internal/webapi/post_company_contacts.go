@@ -0,0 +1,67 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// PostCompanyContacts adds the contact to the company,
+// the primary contact replaces the previous primary contact of its type.
+func (h *HandlerEnv) PostCompanyContacts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return
+	}
+	input := new(InputContact)
+	if err = json.NewDecoder(r.Body).Decode(input); err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+	if validationErrs := input.Validate(); len(validationErrs) > 0 {
+		logger.WithField("validation_errors", validationErrs).Warn("invalid contact")
+		UnprocessableEntity(ctx, w, "invalid contact", validationErrs)
+
+		return
+	}
+
+	createdAt := NewCreatedAt()
+	contact := &db.CompanyContact{
+		ID:        NewContactID(),
+		CompanyID: companyID,
+		Type:      input.Type,
+		Value:     input.Value,
+		Label:     input.Label,
+		Name:      input.Name,
+		Primary:   input.Primary,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	err = withCompanyLocked(ctx, dbConn, companyID, func(tx *sqlx.Tx) error {
+		return db.CreateCompanyContact(ctx, tx, contact)
+	})
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("create contact failed")
+		contactWriteFailed(ctx, w, err)
+
+		return
+	}
+
+	CreatedResponse(ctx, w, newContactResponse(contact))
+}
@@ -0,0 +1,87 @@
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+type InputCompanyTags struct {
+	Tags []string `json:"tags"`
+}
+
+// CompanyTagsResponse lists all tags of the company.
+type CompanyTagsResponse []string
+
+// Validate returns the list of invalid fields, empty list means the tags are valid.
+// Tags must be normalized by uniqueTags.
+func (it *InputCompanyTags) Validate() FieldErrors {
+	errs := make(FieldErrors, 0)
+	switch {
+	case len(it.Tags) == 0:
+		errs.add("tags", "is required")
+	case len(it.Tags) > MaxTagsCount:
+		errs.add("tags", fmt.Sprintf("must contain at most %d tags", MaxTagsCount))
+	}
+	for i, tag := range it.Tags {
+		if message := validateTag(tag); message != "" {
+			errs.add(fmt.Sprintf("tags[%d]", i), message)
+		}
+	}
+
+	return errs
+}
+
+// PostCompanyTags attaches tags to the company, missing tags are created, already attached tags are skipped.
+// New version of the company is saved only if any tag is attached.
+func (h *HandlerEnv) PostCompanyTags(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return
+	}
+	input := new(InputCompanyTags)
+	if err = json.NewDecoder(r.Body).Decode(input); err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+	input.Tags = uniqueTags(input.Tags)
+	if validationErrs := input.Validate(); len(validationErrs) > 0 {
+		logger.WithField("validation_errors", validationErrs).Warn("invalid tags")
+		UnprocessableEntity(ctx, w, "invalid tags", validationErrs)
+
+		return
+	}
+
+	tagIDs := make([]uuid.UUID, 0, len(input.Tags))
+	for range input.Tags {
+		tagIDs = append(tagIDs, NewTagID())
+	}
+	dbCompany, err := changeCompanyTags(ctx, dbConn, companyID, func(tx *sqlx.Tx) (int64, error) {
+		return db.AttachCompanyTags(ctx, tx, companyID, input.Tags, tagIDs, NewCreatedAt())
+	})
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("attach tags failed")
+		companyTagsWriteFailed(ctx, w, err)
+
+		return
+	}
+
+	OKResponse(ctx, w, CompanyTagsResponse(newTagsResponse(dbCompany.Tags)))
+}
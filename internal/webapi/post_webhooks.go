@@ -0,0 +1,198 @@
+package webapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/webhook"
+)
+
+// MaxWebhookURLLength follows the column of webhook_subscriptions table.
+const MaxWebhookURLLength = 2048
+
+// webhookSecretSize is the number of random bytes of the secret.
+const webhookSecretSize = 32
+
+type InputWebhook struct {
+	URL        string                `json:"url"`
+	EventTypes []db.CompanyEventType `json:"event_types"`
+}
+
+type WebhookResponse struct {
+	ID         uuid.UUID             `json:"id"`
+	URL        string                `json:"url"`
+	EventTypes []db.CompanyEventType `json:"event_types"`
+	CreatedAt  time.Time             `json:"created_at"`
+}
+
+// PostWebhookResponse is the only response with the secret, it is not shown again.
+type PostWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+func newWebhookResponse(subscription *db.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+// PostWebhooks registers the URL to receive company events of the given types.
+func (h *HandlerEnv) PostWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	// deliveries are shown to the owner, so the owner must outlive the token
+	owner := authn.Principal(ctx)
+	if owner == "" {
+		logger.Warn("webhook of client without subject")
+		Forbidden(ctx, w, "webhooks are available to clients with subject only")
+
+		return
+	}
+
+	input := new(InputWebhook)
+	err := json.NewDecoder(r.Body).Decode(input)
+	if err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+	allowPrivateNetworks := h.WebhooksConf != nil && h.WebhooksConf.AllowPrivateNetworks
+	if validationErrs := input.Validate(allowPrivateNetworks); len(validationErrs) > 0 {
+		logger.WithField("validation_errors", validationErrs).Warn("invalid webhook")
+		UnprocessableEntity(ctx, w, "invalid webhook", validationErrs)
+
+		return
+	}
+
+	secret, err := NewWebhookSecret()
+	if err != nil {
+		logger.WithError(err).Error("generate webhook secret failed")
+		InternalServerError(ctx, w, "create webhook failed")
+
+		return
+	}
+	subscription := &db.WebhookSubscription{
+		ID:         NewWebhookID(),
+		URL:        input.URL,
+		EventTypes: uniqueEventTypes(input.EventTypes),
+		Secret:     secret,
+		Owner:      owner,
+		CreatedAt:  NewCreatedAt(),
+	}
+	if err = db.CreateWebhookSubscription(ctx, dbConn, subscription); err != nil {
+		logger.WithError(err).Error("create webhook failed")
+		InternalServerError(ctx, w, "create webhook failed")
+
+		return
+	}
+
+	CreatedResponse(ctx, w, PostWebhookResponse{
+		WebhookResponse: newWebhookResponse(subscription),
+		Secret:          subscription.Secret,
+	})
+}
+
+// Validate returns the list of invalid fields, empty list means the webhook is valid.
+// URLs of localhost and of addresses which are not public are invalid unless allowPrivateNetworks is set.
+func (iw *InputWebhook) Validate(allowPrivateNetworks bool) FieldErrors {
+	errs := make(FieldErrors, 0)
+
+	switch {
+	case iw.URL == "":
+		errs.add("url", "is required")
+	case utf8.RuneCountInString(iw.URL) > MaxWebhookURLLength:
+		errs.add("url", fmt.Sprintf("must be at most %d characters", MaxWebhookURLLength))
+	case !isWebURL(iw.URL):
+		errs.add("url", "must be absolute http(s) URL")
+	case !allowPrivateNetworks && !isPublicURL(iw.URL):
+		errs.add("url", "must not point to localhost or private network")
+	}
+
+	if len(iw.EventTypes) == 0 {
+		errs.add("event_types", "is required")
+	}
+	for _, eventType := range iw.EventTypes {
+		if !isCompanyEventType(eventType) {
+			errs.add("event_types", "must be list of "+companyEventTypesList())
+
+			break
+		}
+	}
+
+	return errs
+}
+
+// isPublicURL checks the host of the valid web URL.
+func isPublicURL(rawURL string) bool {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return webhook.IsPublicHost(parsedURL.Hostname())
+}
+
+func isCompanyEventType(eventType db.CompanyEventType) bool {
+	for _, knownType := range db.CompanyEventTypesList {
+		if eventType == knownType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func companyEventTypesList() string {
+	types := make([]string, 0, len(db.CompanyEventTypesList))
+	for _, eventType := range db.CompanyEventTypesList {
+		types = append(types, string(eventType))
+	}
+
+	return strings.Join(types, ", ")
+}
+
+func uniqueEventTypes(eventTypes []db.CompanyEventType) db.CompanyEventTypes {
+	seen := make(map[db.CompanyEventType]bool, len(eventTypes))
+	unique := make(db.CompanyEventTypes, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		if !seen[eventType] {
+			seen[eventType] = true
+			unique = append(unique, eventType)
+		}
+	}
+
+	return unique
+}
+
+func NewWebhookID() uuid.UUID {
+	return uuid.New()
+}
+
+// NewWebhookSecret returns the random hex encoded key of the payload signatures.
+func NewWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
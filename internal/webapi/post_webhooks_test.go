@@ -0,0 +1,201 @@
+package webapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type PostWebhooksSuite struct {
+	dbSuite
+	router              *chi.Mux
+	countryDetectorMock *geoipMocks.CountryDetector
+	testJWT             string
+}
+
+func TestPostWebhooksSuite(t *testing.T) {
+	s := new(PostWebhooksSuite)
+	suite.Run(t, s)
+}
+
+func (s *PostWebhooksSuite) SetupTest() {
+	// webhooks are not restricted by country
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("webhooks-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *PostWebhooksSuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *PostWebhooksSuite) TestPostWebhooks_OK() {
+	t := s.T()
+	webhookID := "3d9e4f50-6172-4c83-ad94-be05f6071823"
+	createdAt := time.Date(2022, 9, 15, 15, 4, 17, 0, time.UTC)
+	fakeIDPatch := gomonkey.ApplyFunc(NewWebhookID, func() uuid.UUID {
+		return uuid.MustParse(webhookID)
+	})
+	defer fakeIDPatch.Reset()
+	fakeCreatedAtPatch := gomonkey.ApplyFunc(NewCreatedAt, func() time.Time {
+		return createdAt
+	})
+	defer fakeCreatedAtPatch.Reset()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/webhooks",
+		strings.NewReader(`{"url": "https://example.com/hooks",`+
+			` "event_types": ["CompanyCreated", "CompanyDeleted", "CompanyCreated"]}`), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusCreated, response.Code, "http code must match")
+
+	// assert HTTP body
+	gotBody := new(struct {
+		Data PostWebhookResponse `json:"data"`
+	})
+	if err := json.NewDecoder(response.Body).Decode(gotBody); err != nil {
+		t.Fatalf("decode response body failed: %s", err)
+	}
+	expectedWebhook := WebhookResponse{
+		ID:         uuid.MustParse(webhookID),
+		URL:        "https://example.com/hooks",
+		EventTypes: []db.CompanyEventType{db.CompanyCreated, db.CompanyDeleted},
+		CreatedAt:  createdAt,
+	}
+	assert.Equal(t, expectedWebhook, gotBody.Data.WebhookResponse, "webhook must match")
+	assert.Len(t, gotBody.Data.Secret, 2*webhookSecretSize, "secret length must match")
+
+	// assert DB
+	ctx := logging.WithContext(context.Background(), s.logger)
+	dbWebhook, err := db.GetWebhookSubscription(ctx, s.dbConn, uuid.MustParse(webhookID))
+	if err != nil {
+		t.Fatalf("get webhook failed: %s", err)
+	}
+	assert.Equal(t, "test/webhooks-tester", dbWebhook.Owner, "owner must match")
+	assert.Equal(t, gotBody.Data.Secret, dbWebhook.Secret, "secret must match")
+}
+
+func (s *PostWebhooksSuite) TestPostWebhooks_Invalid() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/webhooks",
+		strings.NewReader(`{"url": "ftp://example.com", "event_types": ["CompanyMoved"]}`), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code must match")
+
+	// assert HTTP body
+	expectedBody := `{"error":"invalid webhook","details":[` +
+		`{"field":"url","message":"must be absolute http(s) URL"},` +
+		`{"field":"event_types","message":"must be list of ` +
+		`CompanyCreated, CompanyUpdated, CompanyDeleted, CompanyRestored"}]}`
+	assert.JSONEq(t, expectedBody, response.Body.String(), "http body must match")
+}
+
+func (s *PostWebhooksSuite) TestPostWebhooks_PrivateNetwork() {
+	t := s.T()
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/webhooks",
+		strings.NewReader(`{"url": "http://169.254.169.254/latest/meta-data", "event_types": ["CompanyMoved"]}`), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusUnprocessableEntity, response.Code, "http code must match")
+
+	// assert HTTP body
+	expectedBody := `{"error":"invalid webhook","details":[` +
+		`{"field":"url","message":"must not point to localhost or private network"},` +
+		`{"field":"event_types","message":"must be list of ` +
+		`CompanyCreated, CompanyUpdated, CompanyDeleted, CompanyRestored"}]}`
+	assert.JSONEq(t, expectedBody, response.Body.String(), "http body must match")
+}
+
+func (s *PostWebhooksSuite) TestPostWebhooks_NoSubject() {
+	t := s.T()
+	// the token without subject identifies no client beyond its own lifetime
+	noSubjectJWT, err := authn.NewTokenService(s.appConf.ClientToken).IssueToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", noSubjectJWT),
+		},
+	}
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/webhooks",
+		strings.NewReader(`{"url": "https://example.com/hooks", "event_types": ["CompanyCreated"]}`), metadata)
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusForbidden, response.Code, "http code must match")
+
+	// assert HTTP body
+	expectedBody := `{"error":"webhooks are available to clients with subject only"}`
+	assert.JSONEq(t, expectedBody, response.Body.String(), "http body must match")
+}
+
+func (s *PostWebhooksSuite) TestPostWebhooks_Unauthorized() {
+	t := s.T()
+
+	// make request
+	response := makeTestRequest(s.router, http.MethodPost, "/api/v1/webhooks",
+		strings.NewReader(`{"url": "https://example.com/hooks", "event_types": ["CompanyCreated"]}`),
+		&testRequestMetaData{remoteAddr: "127.0.0.1:63099"})
+
+	// assert HTTP code
+	assert.Equal(t, http.StatusUnauthorized, response.Code, "http code must match")
+}
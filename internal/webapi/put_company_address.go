@@ -0,0 +1,57 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// PutCompanyAddress replaces the address of the company.
+func (h *HandlerEnv) PutCompanyAddress(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	companyID, addressID, ok := parseAddressURLParams(ctx, w, r)
+	if !ok {
+		return
+	}
+	input := new(InputAddress)
+	if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+	if validationErrs := input.Validate(); len(validationErrs) > 0 {
+		logger.WithField("validation_errors", validationErrs).Warn("invalid address")
+		UnprocessableEntity(ctx, w, "invalid address", validationErrs)
+
+		return
+	}
+
+	var address *db.CompanyAddress
+	err := withCompanyLocked(ctx, dbConn, companyID, func(tx *sqlx.Tx) error {
+		var getErr error
+		address, getErr = db.GetCompanyAddress(ctx, tx, companyID, addressID)
+		if getErr != nil {
+			return getErr
+		}
+		input.dbAddress(address)
+		address.UpdatedAt = NewUpdatedAt()
+
+		return db.UpdateCompanyAddress(ctx, tx, address)
+	})
+	if err != nil {
+		logger.WithError(err).WithField("address_id", addressID).Error("update address failed")
+		addressWriteFailed(ctx, w, err)
+
+		return
+	}
+
+	OKResponse(ctx, w, newAddressResponse(address))
+}
@@ -0,0 +1,62 @@
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// PutCompanyContact replaces the contact of the company,
+// the primary contact replaces the previous primary contact of its type.
+func (h *HandlerEnv) PutCompanyContact(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	companyID, contactID, ok := parseContactURLParams(ctx, w, r)
+	if !ok {
+		return
+	}
+	input := new(InputContact)
+	if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+	if validationErrs := input.Validate(); len(validationErrs) > 0 {
+		logger.WithField("validation_errors", validationErrs).Warn("invalid contact")
+		UnprocessableEntity(ctx, w, "invalid contact", validationErrs)
+
+		return
+	}
+
+	var contact *db.CompanyContact
+	err := withCompanyLocked(ctx, dbConn, companyID, func(tx *sqlx.Tx) error {
+		var getErr error
+		contact, getErr = db.GetCompanyContact(ctx, tx, companyID, contactID)
+		if getErr != nil {
+			return getErr
+		}
+		contact.Type = input.Type
+		contact.Value = input.Value
+		contact.Label = input.Label
+		contact.Name = input.Name
+		contact.Primary = input.Primary
+		contact.UpdatedAt = NewUpdatedAt()
+
+		return db.UpdateCompanyContact(ctx, tx, contact)
+	})
+	if err != nil {
+		logger.WithError(err).WithField("contact_id", contactID).Error("update contact failed")
+		contactWriteFailed(ctx, w, err)
+
+		return
+	}
+
+	OKResponse(ctx, w, newContactResponse(contact))
+}
@@ -0,0 +1,103 @@
+package webapi
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+type InputCompanyParent struct {
+	// ParentID is null for the top level company.
+	ParentID *uuid.UUID `json:"parent_id"`
+}
+
+type PutCompanyParentResponse struct {
+	CompanyResponse
+}
+
+// PutCompanyParent sets or clears the parent of the company, the same parent is not saved again.
+// The parent can not be the company itself or any of its subsidiaries.
+func (h *HandlerEnv) PutCompanyParent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return
+	}
+	input := new(InputCompanyParent)
+	if err = json.NewDecoder(r.Body).Decode(input); err != nil {
+		logger.WithError(err).Error("decode input failed")
+		BadRequest(ctx, w, "decode request failed")
+
+		return
+	}
+
+	dbCompany, ok := getCompanyForWrite(ctx, w, r, dbConn, companyID)
+	if !ok {
+		return
+	}
+	if sameParent(dbCompany.ParentID, input.ParentID) {
+		response := &PutCompanyParentResponse{CompanyResponse: newCompanyResponse(dbCompany)}
+		w.Header().Set("ETag", companyETag(dbCompany.Version))
+		OKResponse(ctx, w, response)
+
+		return
+	}
+
+	before := *dbCompany
+	dbCompany.ParentID = input.ParentID
+	dbCompany.UpdatedAt = NewUpdatedAt()
+	dbCompany.UpdatedBy = authn.Actor(ctx)
+	err = db.WithTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+		if setErr := db.SetCompanyParent(ctx, tx, dbCompany); setErr != nil {
+			return setErr
+		}
+
+		return recordCompanyChanges(ctx, tx, db.CompanyAuditUpdate, companyChange{Before: &before, After: dbCompany})
+	})
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("set company parent failed")
+		switch {
+		case errors.Is(err, db.ErrParentCompanyNotFound):
+			UnprocessableEntity(ctx, w, "invalid parent", FieldErrors{{Field: "parent_id", Message: "company not found"}})
+		case errors.Is(err, db.ErrCompanyHierarchyCycle):
+			UnprocessableEntity(ctx, w, "invalid parent",
+				FieldErrors{{Field: "parent_id", Message: "must not be the company or its subsidiary"}})
+		case errors.Is(err, sql.ErrNoRows):
+			NotFound(ctx, w, "company not found")
+		case errors.Is(err, db.ErrCompanyVersionMismatch):
+			PreconditionFailed(ctx, w, "company was modified")
+		default:
+			InternalServerError(ctx, w, "set company parent failed")
+		}
+
+		return
+	}
+
+	response := &PutCompanyParentResponse{CompanyResponse: newCompanyResponse(dbCompany)}
+	w.Header().Set("ETag", companyETag(dbCompany.Version))
+	OKResponse(ctx, w, response)
+}
+
+func sameParent(parentID, newParentID *uuid.UUID) bool {
+	if parentID == nil || newParentID == nil {
+		return parentID == newParentID
+	}
+
+	return *parentID == *newParentID
+}
@@ -5,12 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/pzabolotniy/logging/pkg/logging"
 )
 
 type ResponseBody struct {
-	Data  any    `json:"data,omitempty"`
-	Error string `json:"error,omitempty"`
+	Data       any    `json:"data,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	// InvalidIDs and NotFound report requested IDs missing in Data.
+	InvalidIDs []string    `json:"invalid_ids,omitempty"`
+	NotFound   []uuid.UUID `json:"not_found,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Details    any         `json:"details,omitempty"`
 }
 
 type Response struct {
@@ -73,6 +79,23 @@ func OKResponse(ctx context.Context, w http.ResponseWriter, data any) {
 	makeJSONResponse(ctx, w, resp)
 }
 
+func NoContentResponse(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// OKPageResponse responds with the page of the list, nextCursor is empty for the last page.
+func OKPageResponse(ctx context.Context, w http.ResponseWriter, data any, nextCursor string) {
+	respBody := &ResponseBody{
+		Data:       data,
+		NextCursor: nextCursor,
+	}
+	resp := &Response{
+		HTTPStatus: http.StatusOK,
+		HTTPBody:   respBody,
+	}
+	makeJSONResponse(ctx, w, resp)
+}
+
 func Unauthorized(ctx context.Context, w http.ResponseWriter, msg string) {
 	respBody := &ResponseBody{
 		Error: msg,
@@ -95,6 +118,52 @@ func NotFound(ctx context.Context, w http.ResponseWriter, msg string) {
 	makeJSONResponse(ctx, w, resp)
 }
 
+func PreconditionFailed(ctx context.Context, w http.ResponseWriter, msg string) {
+	respBody := &ResponseBody{
+		Error: msg,
+	}
+	resp := &Response{
+		HTTPStatus: http.StatusPreconditionFailed,
+		HTTPBody:   respBody,
+	}
+	makeJSONResponse(ctx, w, resp)
+}
+
+func Conflict(ctx context.Context, w http.ResponseWriter, msg string, details any) {
+	respBody := &ResponseBody{
+		Error:   msg,
+		Details: details,
+	}
+	resp := &Response{
+		HTTPStatus: http.StatusConflict,
+		HTTPBody:   respBody,
+	}
+	makeJSONResponse(ctx, w, resp)
+}
+
+func UnprocessableEntity(ctx context.Context, w http.ResponseWriter, msg string, details any) {
+	respBody := &ResponseBody{
+		Error:   msg,
+		Details: details,
+	}
+	resp := &Response{
+		HTTPStatus: http.StatusUnprocessableEntity,
+		HTTPBody:   respBody,
+	}
+	makeJSONResponse(ctx, w, resp)
+}
+
+func UnsupportedMediaType(ctx context.Context, w http.ResponseWriter, msg string) {
+	respBody := &ResponseBody{
+		Error: msg,
+	}
+	resp := &Response{
+		HTTPStatus: http.StatusUnsupportedMediaType,
+		HTTPBody:   respBody,
+	}
+	makeJSONResponse(ctx, w, resp)
+}
+
 func makeJSONResponse(ctx context.Context, w http.ResponseWriter, resp *Response) {
 	logger := logging.FromContext(ctx)
 	w.Header().Add("Content-Type", "application/json")
@@ -104,3 +173,13 @@ func makeJSONResponse(ctx context.Context, w http.ResponseWriter, resp *Response
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
+
+// makeRawJSONResponse writes the body encoded beforehand, e.g. the response saved by idempotency key.
+func makeRawJSONResponse(ctx context.Context, w http.ResponseWriter, httpStatus int, body []byte) {
+	logger := logging.FromContext(ctx)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	if _, writeErr := w.Write(body); writeErr != nil {
+		logger.WithError(writeErr).Error("write response failed")
+	}
+}
@@ -0,0 +1,87 @@
+package webapi
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+type RestoreCompanyResponse struct {
+	CompanyResponse
+}
+
+// RestoreCompany undoes soft delete of the company.
+// Restoring not deleted company changes nothing.
+func (h *HandlerEnv) RestoreCompany(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.FromContext(ctx)
+	dbConn := h.DbConn
+
+	urlCompanyID := chi.URLParam(r, "companyID")
+	companyID, err := uuid.Parse(urlCompanyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", urlCompanyID).Warn("parse companyID failed")
+		BadRequest(ctx, w, "invalid companyID")
+
+		return
+	}
+
+	dbCompany, err := db.GetCompanyByIDWithDeleted(ctx, dbConn, companyID)
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Warn("get company failed")
+		if errors.Is(err, sql.ErrNoRows) {
+			NotFound(ctx, w, "company not found")
+
+			return
+		}
+		InternalServerError(ctx, w, "get company failed")
+
+		return
+	}
+	if !ifMatch(r, companyETag(dbCompany.Version)) {
+		logger.WithField("company_id", companyID).Warn("company etag mismatch")
+		PreconditionFailed(ctx, w, "company was modified")
+
+		return
+	}
+
+	if dbCompany.DeletedAt != nil {
+		before := *dbCompany
+		dbCompany.UpdatedAt = NewUpdatedAt()
+		dbCompany.UpdatedBy = authn.Actor(ctx)
+		err = db.WithTx(ctx, dbConn, func(tx *sqlx.Tx) error {
+			if restoreErr := db.RestoreCompany(ctx, tx, dbCompany); restoreErr != nil {
+				return restoreErr
+			}
+
+			return recordCompanyChanges(ctx, tx, db.CompanyAuditRestore, companyChange{Before: &before, After: dbCompany})
+		})
+	}
+	if err != nil {
+		logger.WithError(err).WithField("company_id", companyID).Error("restore company failed")
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			NotFound(ctx, w, "company not found")
+		case errors.Is(err, db.ErrCompanyVersionMismatch):
+			PreconditionFailed(ctx, w, "company was modified")
+		default:
+			if !companyConflict(ctx, w, dbConn, err) {
+				InternalServerError(ctx, w, "restore company failed")
+			}
+		}
+
+		return
+	}
+
+	response := &RestoreCompanyResponse{CompanyResponse: newCompanyResponse(dbCompany)}
+	w.Header().Set("ETag", companyETag(dbCompany.Version))
+	OKResponse(ctx, w, response)
+}
@@ -0,0 +1,155 @@
+package webapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/authn"
+	geoipMocks "github.com/pzabolotniy/xm-golang-exercise/internal/geoip/mocks"
+)
+
+type RestoreCompanySuite struct {
+	dbSuite
+	countryDetectorMock *geoipMocks.CountryDetector
+	router              *chi.Mux
+	testJWT             string
+}
+
+func TestRestoreCompanySuite(t *testing.T) {
+	s := new(RestoreCompanySuite)
+	suite.Run(t, s)
+}
+
+func (s *RestoreCompanySuite) SetupSuite() {
+	s.dbSuite.SetupSuite()
+	s.loadFixtures("fixtures/companies.yaml")
+}
+
+func (s *RestoreCompanySuite) SetupTest() {
+	countryDetectorMock := &geoipMocks.CountryDetector{}
+	countryDetectorMock.
+		On("CountryByIP", mock.AnythingOfType("*context.valueCtx"), "127.0.0.1").
+		Return("localhost", nil)
+
+	handler := &HandlerEnv{DbConn: s.dbConn}
+	tokenService := authn.NewTokenService(s.appConf.ClientToken)
+	testJWT, err := tokenService.IssueToken(authn.WithSubject("restore-tester"))
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	routerParams := &RouterParams{
+		Logger:          s.logger,
+		Handler:         handler,
+		CountryDetector: countryDetectorMock,
+		GeoIPConf:       s.appConf.GeoIP,
+		TokenService:    tokenService,
+	}
+	router := CreateRouter(routerParams)
+	s.router = router
+	s.testJWT = testJWT
+	s.countryDetectorMock = countryDetectorMock
+}
+
+func (s *RestoreCompanySuite) TearDownTest() {
+	s.countryDetectorMock.AssertExpectations(s.T())
+}
+
+func (s *RestoreCompanySuite) TestRestoreCompany_OK() {
+	t := s.T()
+
+	// prepare fake variadic parameters
+	fakeTime := time.Date(2022, 9, 18, 11, 30, 0, 0, time.UTC)
+	timePatch := gomonkey.ApplyFunc(NewUpdatedAt, func() time.Time {
+		return fakeTime
+	})
+	defer timePatch.Reset()
+
+	// testdata
+	companyID := "7c2e1f5a-3b4d-4e6f-8a9b-0c1d2e3f4a5b"
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s/restore", companyID)
+	response := makeTestRequest(s.router, http.MethodPost, testURL, nil, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusOK
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"data": {
+		"id": "7c2e1f5a-3b4d-4e6f-8a9b-0c1d2e3f4a5b",
+		"name": "TestRestoreCompany_OK",
+		"code": "RESTORE",
+		"country": "CY",
+		"website": "https://restore.cy",
+		"phone": "+35722000001",
+		"description": "",
+		"employees_count": 0,
+		"registered": false,
+		"type": "Corporation",
+		"created_at": "2022-09-16T16:05:15Z",
+		"updated_at": "2022-09-18T11:30:00Z",
+		"version": 3,
+		"tags": [],
+		"created_by": "",
+		"updated_by": "test/restore-tester"
+	}
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+
+	dbCompany := selectDbCompanyByID(t, s.dbConn, companyID)
+	assert.Nil(t, dbCompany.DeletedAt, "company must be restored")
+}
+
+func (s *RestoreCompanySuite) TestRestoreCompany_NotFound() {
+	t := s.T()
+
+	// testdata
+	companyID := "7c2e1f5a-3b4d-4e6f-8a9b-0c1d2e3f4999"
+
+	// make request
+	metadata := &testRequestMetaData{
+		remoteAddr: "127.0.0.1:63099",
+		headers: map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", s.testJWT),
+		},
+	}
+	testURL := fmt.Sprintf("/api/v1/companies/%s/restore", companyID)
+	response := makeTestRequest(s.router, http.MethodPost, testURL, nil, metadata)
+
+	// assert HTTP code
+	gotHTTPCode := response.Code
+	gotBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read response body failed: %s", err)
+	}
+	expectedHTTPCode := http.StatusNotFound
+	assert.Equal(t, expectedHTTPCode, gotHTTPCode, "http code must match")
+
+	// assert HTTP body
+	expectedHTTPBody := `{
+	"error": "company not found"
+}`
+	assert.JSONEq(t, expectedHTTPBody, string(gotBody), "body must match")
+}
@@ -40,11 +40,51 @@ func CreateRouter(params *RouterParams) *chi.Mux {
 					WithCountryRestriction(countryDetector, geoIPConf.AllowedCountryName),
 				)
 				restrictedRouter.Post("/", handler.PostCompanies)
+				restrictedRouter.Post("/import", handler.PostCompaniesImport)
+				restrictedRouter.Patch("/{companyID}", handler.PatchCompany)
 				restrictedRouter.Delete("/{companyID}", handler.DeleteCompany)
+				restrictedRouter.Post("/{companyID}/restore", handler.RestoreCompany)
+				restrictedRouter.Post("/{companyID}/contacts", handler.PostCompanyContacts)
+				restrictedRouter.Put("/{companyID}/contacts/{contactID}", handler.PutCompanyContact)
+				restrictedRouter.Delete("/{companyID}/contacts/{contactID}", handler.DeleteCompanyContact)
+				restrictedRouter.Post("/{companyID}/addresses", handler.PostCompanyAddresses)
+				restrictedRouter.Put("/{companyID}/addresses/{addressID}", handler.PutCompanyAddress)
+				restrictedRouter.Delete("/{companyID}/addresses/{addressID}", handler.DeleteCompanyAddress)
+				restrictedRouter.Post("/{companyID}/tags", handler.PostCompanyTags)
+				restrictedRouter.Delete("/{companyID}/tags/{tag}", handler.DeleteCompanyTag)
+				restrictedRouter.Put("/{companyID}/parent", handler.PutCompanyParent)
 			})
-			companiesRouter.Get("/{companyID}", handler.GetCompany)
+			companiesRouter.With(WithAuthN(tokenService)).Get("/{companyID}/history", handler.GetCompanyHistory)
+			companiesRouter.With(WithAuthN(tokenService)).Get("/events", handler.GetCompanyEvents)
+			companiesRouter.With(WithAuthN(tokenService)).Get("/export", handler.GetCompaniesExport)
+			companiesRouter.Group(func(publicRouter chi.Router) {
+				publicRouter.Use(WithOptionalAuthN(tokenService))
+				publicRouter.Get("/", handler.GetCompanies)
+				publicRouter.Get("/{companyID}", handler.GetCompany)
+				publicRouter.Get("/{companyID}/contacts", handler.GetCompanyContacts)
+				publicRouter.Get("/{companyID}/addresses", handler.GetCompanyAddresses)
+				publicRouter.Get("/{companyID}/children", handler.GetCompanyChildren)
+				publicRouter.Get("/{companyID}/ancestors", handler.GetCompanyAncestors)
+				publicRouter.Get("/{companyID}/tree", handler.GetCompanyTree)
+			})
+		})
+		apiV1Router.Group(func(batchRouter chi.Router) {
+			batchRouter.Use(
+				WithAuthN(tokenService),
+				WithCountryRestriction(countryDetector, geoIPConf.AllowedCountryName),
+			)
+			batchRouter.Post("/companies:batch", handler.PostCompaniesBatch)
+			batchRouter.Post("/companies:batchDelete", handler.PostCompaniesBatchDelete)
+		})
+		apiV1Router.With(WithOptionalAuthN(tokenService)).Post("/search/companies", handler.PostCompaniesSearch)
+		apiV1Router.With(WithAuthN(tokenService)).Get("/tags", handler.GetTags)
+		apiV1Router.Route("/webhooks", func(webhooksRouter chi.Router) {
+			webhooksRouter.Use(WithAuthN(tokenService))
+			webhooksRouter.Post("/", handler.PostWebhooks)
+			webhooksRouter.Get("/", handler.GetWebhooks)
+			webhooksRouter.Delete("/{webhookID}", handler.DeleteWebhook)
+			webhooksRouter.Get("/{webhookID}/deliveries", handler.GetWebhookDeliveries)
 		})
-		apiV1Router.Post("/search/companies", handler.PostCompaniesSearch)
 	})
 
 	return router
@@ -0,0 +1,156 @@
+package webapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+// Limits follow the columns of companies table.
+const (
+	MaxCodeLength        = 16
+	MaxWebSiteLength     = 2048
+	MaxPhoneLength       = 64
+	MinPhoneDigits       = 4
+	MaxPhoneDigits       = 15 // E.164 limit
+	MaxDescriptionLength = 3000
+	MaxEmployeesCount    = math.MaxInt32 // integer column
+)
+
+var phoneRe = regexp.MustCompile(`^\+?[0-9 ()\-.]+$`)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type FieldErrors []FieldError
+
+func (fe *FieldErrors) add(field, message string) {
+	*fe = append(*fe, FieldError{Field: field, Message: message})
+}
+
+// Validate returns the list of invalid fields, empty list means the company is valid.
+func (ic *InputCompany) Validate() FieldErrors {
+	errs := make(FieldErrors, 0)
+
+	if ic.Name == "" {
+		errs.add("name", "is required")
+	}
+
+	switch {
+	case ic.Code == "":
+		errs.add("code", "is required")
+	case utf8.RuneCountInString(ic.Code) > MaxCodeLength:
+		errs.add("code", fmt.Sprintf("must be at most %d characters", MaxCodeLength))
+	}
+
+	switch {
+	case ic.Country == "":
+		errs.add("country", "is required")
+	case !isCountryCode(ic.Country):
+		errs.add("country", "must be ISO 3166-1 alpha-2 code")
+	}
+
+	switch {
+	case ic.WebSite == "":
+	case utf8.RuneCountInString(ic.WebSite) > MaxWebSiteLength:
+		errs.add("website", fmt.Sprintf("must be at most %d characters", MaxWebSiteLength))
+	case !isWebURL(ic.WebSite):
+		errs.add("website", "must be absolute http(s) URL")
+	}
+
+	switch {
+	case ic.Phone == "":
+	case utf8.RuneCountInString(ic.Phone) > MaxPhoneLength:
+		errs.add("phone", fmt.Sprintf("must be at most %d characters", MaxPhoneLength))
+	case !isPhone(ic.Phone):
+		errs.add("phone", fmt.Sprintf("must be phone number of %d-%d digits", MinPhoneDigits, MaxPhoneDigits))
+	}
+
+	if utf8.RuneCountInString(ic.Description) > MaxDescriptionLength {
+		errs.add("description", fmt.Sprintf("must be at most %d characters", MaxDescriptionLength))
+	}
+
+	switch {
+	case ic.EmployeesCount < 0:
+		errs.add("employees_count", "must not be negative")
+	case ic.EmployeesCount > MaxEmployeesCount:
+		errs.add("employees_count", fmt.Sprintf("must be at most %d", MaxEmployeesCount))
+	}
+
+	switch {
+	case ic.Type == "":
+		errs.add("type", "is required")
+	case !isCompanyType(ic.Type):
+		errs.add("type", "must be one of "+companyTypesList())
+	}
+
+	return errs
+}
+
+// isValidCompany responds with 422 and returns false when the company is not valid.
+func isValidCompany(ctx context.Context, w http.ResponseWriter, input *InputCompany) bool {
+	validationErrs := input.Validate()
+	if len(validationErrs) == 0 {
+		return true
+	}
+	logger := logging.FromContext(ctx)
+	logger.WithField("validation_errors", validationErrs).Warn("invalid company")
+	UnprocessableEntity(ctx, w, "invalid company", validationErrs)
+
+	return false
+}
+
+func isCompanyType(companyType db.CompanyType) bool {
+	for _, knownType := range db.CompanyTypes {
+		if companyType == knownType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func companyTypesList() string {
+	types := make([]string, 0, len(db.CompanyTypes))
+	for _, companyType := range db.CompanyTypes {
+		types = append(types, string(companyType))
+	}
+
+	return strings.Join(types, ", ")
+}
+
+func isWebURL(rawURL string) bool {
+	parsedURL, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") && parsedURL.Host != ""
+}
+
+// isPhone accepts E.164-like numbers: optional leading plus, digits and common separators.
+func isPhone(phone string) bool {
+	if !phoneRe.MatchString(phone) {
+		return false
+	}
+	digits := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+
+	return digits >= MinPhoneDigits && digits <= MaxPhoneDigits
+}
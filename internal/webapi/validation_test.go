@@ -0,0 +1,109 @@
+package webapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+func TestInputCompany_Validate(t *testing.T) {
+	validCompany := func() InputCompany {
+		return InputCompany{
+			Name:    "ltd",
+			Code:    "007",
+			Country: "md",
+			WebSite: "http://google.com",
+			Phone:   "+995 (98) 765-54-43",
+
+			Description:    "Search engine",
+			EmployeesCount: 100,
+			Registered:     true,
+			Type:           db.CompanyTypeSoleProprietorship,
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		modify   func(ic *InputCompany)
+		expected FieldErrors
+	}{
+		{
+			name:     "valid",
+			modify:   func(ic *InputCompany) {},
+			expected: FieldErrors{},
+		},
+		{
+			name: "optional fields are empty",
+			modify: func(ic *InputCompany) {
+				ic.WebSite = ""
+				ic.Phone = ""
+				ic.Description = ""
+				ic.EmployeesCount = 0
+				ic.Registered = false
+			},
+			expected: FieldErrors{},
+		},
+		{
+			name: "required fields are empty",
+			modify: func(ic *InputCompany) {
+				ic.Name = ""
+				ic.Code = ""
+				ic.Country = ""
+				ic.Type = ""
+			},
+			expected: FieldErrors{
+				{Field: "name", Message: "is required"},
+				{Field: "code", Message: "is required"},
+				{Field: "country", Message: "is required"},
+				{Field: "type", Message: "is required"},
+			},
+		},
+		{
+			name: "too long values",
+			modify: func(ic *InputCompany) {
+				ic.Code = strings.Repeat("x", MaxCodeLength+1)
+				ic.WebSite = "https://" + strings.Repeat("x", MaxWebSiteLength)
+				ic.Phone = "+1" + strings.Repeat(" ", MaxPhoneLength)
+				ic.Description = strings.Repeat("x", MaxDescriptionLength+1)
+				ic.EmployeesCount = MaxEmployeesCount + 1
+			},
+			expected: FieldErrors{
+				{Field: "code", Message: "must be at most 16 characters"},
+				{Field: "website", Message: "must be at most 2048 characters"},
+				{Field: "phone", Message: "must be at most 64 characters"},
+				{Field: "description", Message: "must be at most 3000 characters"},
+				{Field: "employees_count", Message: "must be at most 2147483647"},
+			},
+		},
+		{
+			name: "malformed values",
+			modify: func(ic *InputCompany) {
+				ic.Country = "Moldova"
+				ic.WebSite = "ftp://google.com"
+				ic.Phone = "+12"
+				ic.EmployeesCount = -1
+				ic.Type = "corporation"
+			},
+			expected: FieldErrors{
+				{Field: "country", Message: "must be ISO 3166-1 alpha-2 code"},
+				{Field: "website", Message: "must be absolute http(s) URL"},
+				{Field: "phone", Message: "must be phone number of 4-15 digits"},
+				{Field: "employees_count", Message: "must not be negative"},
+				{Field: "type", Message: "must be one of Corporation, NonProfit, Cooperative, Sole Proprietorship"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			input := validCompany()
+			testCase.modify(&input)
+			got := input.Validate()
+			assert.Equal(t, testCase.expected, got, "validation errors must match")
+		})
+	}
+}
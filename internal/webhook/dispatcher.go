@@ -0,0 +1,238 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+const (
+	EventIDHeader   = "X-Webhook-Event-ID"
+	EventTypeHeader = "X-Webhook-Event-Type"
+	TimestampHeader = "X-Webhook-Timestamp"
+	// SignatureHeader is "sha256=" and hex encoded HMAC-SHA256 of the timestamp, "." and the body.
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	DefaultMaxAttempts  = 8
+	DefaultBackoffBase  = 10 * time.Second
+	DefaultBackoffMax   = time.Hour
+	DefaultTimeout      = 10 * time.Second
+	DefaultBatchSize    = 20
+	DefaultPollInterval = time.Second
+)
+
+// maxErrorBodySize limits the part of the failed response body recorded as the error.
+const maxErrorBodySize = 512
+
+// Dispatcher delivers scheduled events to webhooks, failed deliveries are retried with exponential backoff
+// until MaxAttempts is reached, then the delivery is dead.
+type Dispatcher struct {
+	DbConn       *sqlx.DB
+	Client       *http.Client
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	BatchSize    int
+	PollInterval time.Duration
+}
+
+// NewDispatcher makes the dispatcher by the config, defaults are used for missing settings or missing config.
+func NewDispatcher(dbConn *sqlx.DB, webhooksConf *config.Webhooks) *Dispatcher {
+	if webhooksConf == nil {
+		webhooksConf = new(config.Webhooks)
+	}
+	timeout := DefaultTimeout
+	if webhooksConf.Timeout > 0 {
+		timeout = webhooksConf.Timeout
+	}
+	dispatcher := &Dispatcher{
+		DbConn:       dbConn,
+		Client:       newHTTPClient(timeout, webhooksConf.AllowPrivateNetworks),
+		MaxAttempts:  DefaultMaxAttempts,
+		BackoffBase:  DefaultBackoffBase,
+		BackoffMax:   DefaultBackoffMax,
+		BatchSize:    DefaultBatchSize,
+		PollInterval: DefaultPollInterval,
+	}
+	if webhooksConf.MaxAttempts > 0 {
+		dispatcher.MaxAttempts = webhooksConf.MaxAttempts
+	}
+	if webhooksConf.BackoffBase > 0 {
+		dispatcher.BackoffBase = webhooksConf.BackoffBase
+	}
+	if webhooksConf.BackoffMax > 0 {
+		dispatcher.BackoffMax = webhooksConf.BackoffMax
+	}
+	if webhooksConf.BatchSize > 0 {
+		dispatcher.BatchSize = webhooksConf.BatchSize
+	}
+	if webhooksConf.PollInterval > 0 {
+		dispatcher.PollInterval = webhooksConf.PollInterval
+	}
+
+	return dispatcher
+}
+
+// Run dispatches deliveries until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+	for {
+		dispatched, err := d.DispatchBatch(ctx)
+		if err != nil {
+			logger.WithError(err).Error("dispatch webhook deliveries failed")
+		}
+		// full batch means there can be more due deliveries
+		if err == nil && dispatched == d.BatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchBatch makes one attempt of every due delivery of the batch and returns the number of attempts.
+// Deliveries are claimed for the time of the batch, requests are made outside of transactions
+// and every attempt is saved by its own transaction, so saved attempts are kept if saving another one fails.
+func (d *Dispatcher) DispatchBatch(ctx context.Context) (int, error) {
+	logger := logging.FromContext(ctx)
+	now := time.Now().UTC()
+	// requests are made one by one, each of them takes up to the timeout of the client
+	leaseUntil := now.Add(d.Client.Timeout * time.Duration(d.BatchSize+1))
+	deliveries, err := db.ClaimDueWebhookDeliveries(ctx, d.DbConn, now, leaseUntil, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+	var saveErr error
+	for i := range deliveries {
+		delivery := &deliveries[i].WebhookDelivery
+		attempt := d.Deliver(ctx, &deliveries[i])
+		d.applyAttempt(delivery, attempt)
+		err = db.WithTx(ctx, d.DbConn, func(tx *sqlx.Tx) error {
+			return db.SaveWebhookDeliveryAttempt(ctx, tx, delivery, attempt)
+		})
+		if err != nil {
+			// the delivery is attempted again when the claim expires
+			logger.WithError(err).WithField("delivery_id", delivery.ID).Error("save webhook delivery attempt failed")
+			saveErr = err
+
+			continue
+		}
+		dispatched++
+	}
+
+	return dispatched, saveErr
+}
+
+// Deliver posts the signed payload to the webhook, any 2xx response means the delivery succeeded.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery *db.DueWebhookDelivery) *db.WebhookDeliveryAttempt {
+	logger := logging.FromContext(ctx).WithFields(logging.Fields{
+		"delivery_id": delivery.ID,
+		"webhook_id":  delivery.SubscriptionID,
+	})
+	attemptedAt := time.Now().UTC()
+	attempt := &db.WebhookDeliveryAttempt{
+		DeliveryID:  delivery.ID,
+		AttemptedAt: attemptedAt,
+	}
+	defer func() {
+		attempt.DurationMs = time.Since(attemptedAt).Milliseconds()
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = fmt.Sprintf("create request failed: %s", err)
+
+		return attempt
+	}
+	timestamp := attemptedAt.Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventIDHeader, strconv.FormatInt(delivery.EventID, 10))
+	request.Header.Set(EventTypeHeader, string(delivery.EventType))
+	request.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	response, err := d.Client.Do(request)
+	if err != nil {
+		attempt.Error = fmt.Sprintf("make request failed: %s", err)
+		logger.WithError(err).Warn("deliver webhook failed")
+
+		return attempt
+	}
+	defer func() {
+		if closeErr := response.Body.Close(); closeErr != nil {
+			logger.WithError(closeErr).Error("close webhook response body failed")
+		}
+	}()
+	attempt.StatusCode = response.StatusCode
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+		attempt.Error = fmt.Sprintf("unexpected status code %d: %s", response.StatusCode, body)
+		logger.WithField("status_code", response.StatusCode).Warn("deliver webhook failed")
+	}
+
+	return attempt
+}
+
+// applyAttempt moves the delivery to the state after the attempt.
+func (d *Dispatcher) applyAttempt(delivery *db.WebhookDelivery, attempt *db.WebhookDeliveryAttempt) {
+	delivery.Attempts++
+	delivery.LastError = attempt.Error
+	switch {
+	case attempt.Error == "":
+		delivery.Status = db.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &attempt.AttemptedAt
+	case delivery.Attempts >= d.MaxAttempts:
+		delivery.Status = db.WebhookDeliveryDead
+	default:
+		delivery.NextAttemptAt = attempt.AttemptedAt.Add(Backoff(delivery.Attempts, d.BackoffBase, d.BackoffMax))
+	}
+}
+
+// Sign returns the value of SignatureHeader.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the delay before the next attempt after failedAttempts attempts:
+// base, 2*base, 4*base and so on, but not more than maxDelay.
+func Backoff(failedAttempts int, base, maxDelay time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < failedAttempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	if delay > maxDelay {
+		return maxDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pzabolotniy/logging/pkg/logging"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/config"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+)
+
+func TestSign(t *testing.T) {
+	signature := Sign("secret", 1663254257, []byte(`{"id":1}`))
+
+	// echo -n '1663254257.{"id":1}' | openssl dgst -sha256 -hmac secret
+	expectedSignature := "sha256=ca61157afad4c465bc9c3c83aee2c7a96d229f891eb48c3f289c311198a00b8d"
+	assert.Equal(t, expectedSignature, signature, "signature must match")
+}
+
+func TestBackoff(t *testing.T) {
+	base := 10 * time.Second
+	maxDelay := time.Minute
+	testCases := []struct {
+		failedAttempts int
+		expected       time.Duration
+	}{
+		{failedAttempts: 1, expected: 10 * time.Second},
+		{failedAttempts: 2, expected: 20 * time.Second},
+		{failedAttempts: 3, expected: 40 * time.Second},
+		{failedAttempts: 4, expected: time.Minute},
+		{failedAttempts: 100, expected: time.Minute},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, Backoff(tc.failedAttempts, base, maxDelay),
+			"delay after %d attempts must match", tc.failedAttempts)
+	}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	payload := []byte(`{"id":7,"type":"CompanyCreated"}`)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		assert.Equal(t, payload, body, "body must match")
+		assert.Equal(t, "7", r.Header.Get(EventIDHeader), "event id must match")
+		assert.Equal(t, "CompanyCreated", r.Header.Get(EventTypeHeader), "event type must match")
+		assert.Equal(t, Sign("secret", timestamp, body), r.Header.Get(SignatureHeader), "signature must match")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+	dispatcher := NewDispatcher(nil, &config.Webhooks{AllowPrivateNetworks: true})
+	delivery := &db.DueWebhookDelivery{
+		WebhookDelivery: db.WebhookDelivery{
+			ID:             3,
+			SubscriptionID: uuid.MustParse("3997db3d-f747-4f00-adf8-1d2c71d2a911"),
+			EventID:        7,
+			EventType:      db.CompanyCreated,
+			Payload:        payload,
+			Status:         db.WebhookDeliveryPending,
+		},
+		URL:    receiver.URL,
+		Secret: "secret",
+	}
+
+	attempt := dispatcher.Deliver(ctx, delivery)
+
+	assert.Equal(t, int64(3), attempt.DeliveryID, "delivery id must match")
+	assert.Equal(t, http.StatusOK, attempt.StatusCode, "status code must match")
+	assert.Empty(t, attempt.Error, "error must be empty")
+}
+
+func TestNewDispatcher_NoConfig(t *testing.T) {
+	dispatcher := NewDispatcher(nil, nil)
+	assert.Equal(t, DefaultTimeout, dispatcher.Client.Timeout, "timeout must match")
+	assert.Equal(t, DefaultMaxAttempts, dispatcher.MaxAttempts, "max attempts must match")
+	assert.Equal(t, DefaultBatchSize, dispatcher.BatchSize, "batch size must match")
+}
+
+func TestDispatcher_applyAttempt(t *testing.T) {
+	attemptedAt := time.Date(2022, 9, 15, 15, 4, 17, 0, time.UTC)
+	dispatcher := &Dispatcher{MaxAttempts: 3, BackoffBase: time.Second, BackoffMax: time.Hour}
+	failedAttempt := &db.WebhookDeliveryAttempt{AttemptedAt: attemptedAt, StatusCode: http.StatusBadGateway, Error: "bad"}
+
+	delivery := &db.WebhookDelivery{Status: db.WebhookDeliveryPending, Attempts: 1}
+	dispatcher.applyAttempt(delivery, failedAttempt)
+	assert.Equal(t, db.WebhookDeliveryPending, delivery.Status, "failed delivery must be retried")
+	assert.Equal(t, 2, delivery.Attempts, "attempts must match")
+	assert.Equal(t, attemptedAt.Add(2*time.Second), delivery.NextAttemptAt, "next attempt must match")
+	assert.Equal(t, "bad", delivery.LastError, "last error must match")
+
+	dispatcher.applyAttempt(delivery, failedAttempt)
+	assert.Equal(t, db.WebhookDeliveryDead, delivery.Status, "delivery must be dead after max attempts")
+
+	delivery = &db.WebhookDelivery{Status: db.WebhookDeliveryPending, Attempts: 2}
+	dispatcher.applyAttempt(delivery, &db.WebhookDeliveryAttempt{AttemptedAt: attemptedAt, StatusCode: http.StatusOK})
+	assert.Equal(t, db.WebhookDeliveryDelivered, delivery.Status, "status must match")
+	assert.Equal(t, &attemptedAt, delivery.DeliveredAt, "delivered_at must match")
+}
+
+func TestDispatcher_Deliver_PrivateNetwork(t *testing.T) {
+	ctx := logging.WithContext(context.Background(), logging.GetLogger())
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("webhook must not be delivered to loopback")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+	dispatcher := NewDispatcher(nil, &config.Webhooks{})
+	delivery := &db.DueWebhookDelivery{
+		WebhookDelivery: db.WebhookDelivery{ID: 3, EventID: 7, EventType: db.CompanyCreated, Payload: []byte(`{}`)},
+		URL:             receiver.URL,
+		Secret:          "secret",
+	}
+
+	attempt := dispatcher.Deliver(ctx, delivery)
+
+	assert.Zero(t, attempt.StatusCode, "status code must be empty")
+	assert.Contains(t, attempt.Error, ErrForbiddenTarget.Error(), "error must match")
+}
@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pzabolotniy/logging/pkg/logging"
+
+	"github.com/pzabolotniy/xm-golang-exercise/internal/db"
+	"github.com/pzabolotniy/xm-golang-exercise/internal/outbox"
+)
+
+// Publisher schedules delivery of outbox events to webhooks subscribed to them,
+// the deliveries are made by Dispatcher.
+type Publisher struct {
+	DbConn *sqlx.DB
+}
+
+func NewPublisher(dbConn *sqlx.DB) *Publisher {
+	return &Publisher{DbConn: dbConn}
+}
+
+// Publish schedules the event for webhooks of the tenant of the event.
+func (p *Publisher) Publish(ctx context.Context, event *outbox.Event) error {
+	ctx = db.WithTenantID(ctx, event.TenantID)
+	logger := logging.FromContext(ctx)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event failed: %w", err)
+	}
+	scheduled, err := db.CreateWebhookDeliveries(ctx, p.DbConn, event.ID, event.Type, payload, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("schedule webhook deliveries failed: %w", err)
+	}
+	logger.
+		WithFields(logging.Fields{"event_id": event.ID, "deliveries": scheduled}).
+		Trace("webhook deliveries scheduled")
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublicHost(t *testing.T) {
+	testCases := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "example.com", expected: true},
+		{host: "93.184.216.34", expected: true},
+		{host: "2606:2800:220:1:248:1893:25c8:1946", expected: true},
+		{host: "", expected: false},
+		{host: "localhost", expected: false},
+		{host: "LOCALHOST.", expected: false},
+		{host: "api.localhost", expected: false},
+		{host: "127.0.0.1", expected: false},
+		{host: "::1", expected: false},
+		{host: "10.1.2.3", expected: false},
+		{host: "172.16.0.1", expected: false},
+		{host: "192.168.1.1", expected: false},
+		{host: "169.254.169.254", expected: false},
+		{host: "fe80::1%eth0", expected: false},
+		{host: "fd00::1", expected: false},
+		{host: "0.0.0.0", expected: false},
+		{host: "100.64.0.1", expected: false},
+		{host: "::ffff:127.0.0.1", expected: false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, IsPublicHost(tc.host), "host %q must match", tc.host)
+	}
+}
+
+func TestPublicOnlyControl(t *testing.T) {
+	assert.NoError(t, publicOnlyControl("tcp4", "93.184.216.34:443", nil), "public address must be allowed")
+	assert.ErrorIs(t, publicOnlyControl("tcp4", "169.254.169.254:80", nil), ErrForbiddenTarget,
+		"metadata address must be refused")
+	assert.ErrorIs(t, publicOnlyControl("tcp6", "[::1]:80", nil), ErrForbiddenTarget, "loopback must be refused")
+}
@@ -0,0 +1,15 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE companies
+    ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1, -- incremented on every write, used for optimistic locking
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITHOUT TIME ZONE;
+UPDATE companies SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE companies ALTER COLUMN updated_at SET NOT NULL;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+ALTER TABLE companies
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS version;
+-- +migrate StatementEnd
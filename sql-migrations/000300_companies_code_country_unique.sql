@@ -0,0 +1,9 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS companies_code_country_key ON companies (code, upper(country));
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP INDEX IF EXISTS companies_code_country_key;
+-- +migrate StatementEnd
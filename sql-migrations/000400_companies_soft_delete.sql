@@ -0,0 +1,16 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITHOUT TIME ZONE; -- set by soft delete
+DROP INDEX IF EXISTS companies_code_country_key;
+CREATE UNIQUE INDEX companies_code_country_key ON companies (code, upper(country)) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS companies_deleted_at_idx ON companies (deleted_at) WHERE deleted_at IS NOT NULL;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DELETE FROM companies WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS companies_deleted_at_idx;
+DROP INDEX IF EXISTS companies_code_country_key;
+CREATE UNIQUE INDEX companies_code_country_key ON companies (code, upper(country));
+ALTER TABLE companies DROP COLUMN IF EXISTS deleted_at;
+-- +migrate StatementEnd
@@ -0,0 +1,11 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE INDEX IF NOT EXISTS companies_created_at_id_idx ON companies (created_at, id);
+CREATE INDEX IF NOT EXISTS companies_name_id_idx ON companies (name, id);
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP INDEX IF EXISTS companies_name_id_idx;
+DROP INDEX IF EXISTS companies_created_at_id_idx;
+-- +migrate StatementEnd
@@ -0,0 +1,19 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', name), 'A') || setweight(to_tsvector('simple', code), 'B')
+) STORED;
+CREATE INDEX IF NOT EXISTS companies_search_vector_idx ON companies USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS companies_name_trgm_idx ON companies USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS companies_code_trgm_idx ON companies USING GIN (code gin_trgm_ops);
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP INDEX IF EXISTS companies_code_trgm_idx;
+DROP INDEX IF EXISTS companies_name_trgm_idx;
+DROP INDEX IF EXISTS companies_search_vector_idx;
+ALTER TABLE companies DROP COLUMN IF EXISTS search_vector;
+DROP EXTENSION IF EXISTS pg_trgm;
+-- +migrate StatementEnd
@@ -0,0 +1,20 @@
+-- +migrate Up
+-- +migrate StatementBegin
+ALTER TABLE companies
+    ADD COLUMN IF NOT EXISTS description varchar(3000) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS employees_count integer NOT NULL DEFAULT 0 CHECK (employees_count >= 0),
+    ADD COLUMN IF NOT EXISTS registered boolean NOT NULL DEFAULT FALSE,
+    -- existing companies become corporations, new ones must have the type set explicitly
+    ADD COLUMN IF NOT EXISTS type varchar(32) NOT NULL DEFAULT 'Corporation'
+        CONSTRAINT companies_type_check CHECK (type IN ('Corporation', 'NonProfit', 'Cooperative', 'Sole Proprietorship'));
+ALTER TABLE companies ALTER COLUMN type DROP DEFAULT;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+ALTER TABLE companies
+    DROP COLUMN IF EXISTS type,
+    DROP COLUMN IF EXISTS registered,
+    DROP COLUMN IF EXISTS employees_count,
+    DROP COLUMN IF EXISTS description;
+-- +migrate StatementEnd
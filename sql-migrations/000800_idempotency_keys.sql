@@ -0,0 +1,18 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key varchar(255) PRIMARY KEY,
+    request_hash varchar(64) NOT NULL, -- hex encoded sha256 of the request
+    response_status integer NOT NULL,
+    response_body bytea NOT NULL,
+    response_etag varchar(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TABLE IF EXISTS idempotency_keys;
+-- +migrate StatementEnd
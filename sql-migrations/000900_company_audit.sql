@@ -0,0 +1,30 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE IF NOT EXISTS company_audit (
+    id bigserial PRIMARY KEY,
+    company_id uuid NOT NULL, -- no foreign key, history outlives purged companies
+    action varchar(16) NOT NULL CONSTRAINT company_audit_action_check CHECK (action IN ('create', 'update', 'delete', 'restore')),
+    actor varchar(255) NOT NULL,
+    request_id uuid,
+    before jsonb, -- NULL on create
+    after jsonb NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS company_audit_company_id_idx ON company_audit (company_id, id);
+
+-- the history is append-only
+CREATE OR REPLACE FUNCTION company_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'company_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER company_audit_append_only
+    BEFORE UPDATE OR DELETE ON company_audit
+    FOR EACH STATEMENT EXECUTE FUNCTION company_audit_append_only();
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TABLE IF EXISTS company_audit;
+DROP FUNCTION IF EXISTS company_audit_append_only();
+-- +migrate StatementEnd
@@ -0,0 +1,19 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE IF NOT EXISTS company_outbox (
+    id bigserial PRIMARY KEY,
+    type varchar(32) NOT NULL,
+    company_id uuid NOT NULL,
+    payload jsonb NOT NULL,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    delivered_at TIMESTAMP WITHOUT TIME ZONE, -- NULL until the event is published
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS company_outbox_pending_idx ON company_outbox (id) WHERE delivered_at IS NULL;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TABLE IF EXISTS company_outbox;
+-- +migrate StatementEnd
@@ -0,0 +1,22 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE IF NOT EXISTS company_contacts (
+    id uuid PRIMARY KEY,
+    company_id uuid NOT NULL REFERENCES companies (id) ON DELETE CASCADE,
+    type varchar(16) NOT NULL CONSTRAINT company_contacts_type_check CHECK (type IN ('phone', 'email', 'fax')),
+    value varchar(320) NOT NULL,
+    label varchar(64) NOT NULL DEFAULT '',
+    name varchar(255) NOT NULL DEFAULT '', -- contact person
+    is_primary boolean NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS company_contacts_company_id_idx ON company_contacts (company_id);
+-- the company has at most one primary contact of each type
+CREATE UNIQUE INDEX IF NOT EXISTS company_contacts_primary_key ON company_contacts (company_id, type) WHERE is_primary;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TABLE IF EXISTS company_contacts;
+-- +migrate StatementEnd
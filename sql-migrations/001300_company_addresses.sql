@@ -0,0 +1,27 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE IF NOT EXISTS company_addresses (
+    id uuid PRIMARY KEY,
+    company_id uuid NOT NULL REFERENCES companies (id) ON DELETE CASCADE,
+    type varchar(16) NOT NULL
+        CONSTRAINT company_addresses_type_check CHECK (type IN ('registered', 'operational', 'billing')),
+    street varchar(255) NOT NULL,
+    city varchar(128) NOT NULL,
+    postcode varchar(16) NOT NULL DEFAULT '',
+    region varchar(128) NOT NULL DEFAULT '',
+    country char(2) NOT NULL, -- ISO 3166-1 alpha-2 code in upper case
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS company_addresses_company_id_idx ON company_addresses (company_id);
+-- filter of companies by address
+CREATE INDEX IF NOT EXISTS company_addresses_country_city_idx ON company_addresses (country, lower(city));
+-- the company has at most one registered address
+CREATE UNIQUE INDEX IF NOT EXISTS company_addresses_registered_key ON company_addresses (company_id)
+    WHERE type = 'registered';
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TABLE IF EXISTS company_addresses;
+-- +migrate StatementEnd
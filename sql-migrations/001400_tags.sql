@@ -0,0 +1,23 @@
+-- +migrate Up
+-- +migrate StatementBegin
+CREATE TABLE IF NOT EXISTS tags (
+    id uuid PRIMARY KEY,
+    name varchar(64) NOT NULL, -- in lower case
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    CONSTRAINT tags_name_key UNIQUE (name)
+);
+CREATE TABLE IF NOT EXISTS company_tags (
+    company_id uuid NOT NULL REFERENCES companies (id) ON DELETE CASCADE,
+    tag_id uuid NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    PRIMARY KEY (company_id, tag_id)
+);
+-- filter of companies by tags
+CREATE INDEX IF NOT EXISTS company_tags_tag_id_idx ON company_tags (tag_id);
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP TABLE IF EXISTS company_tags;
+DROP TABLE IF EXISTS tags;
+-- +migrate StatementEnd
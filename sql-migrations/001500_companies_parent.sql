@@ -0,0 +1,14 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- parent company of the subsidiary, purge of the parent makes the subsidiary top level
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS parent_id uuid
+    CONSTRAINT companies_parent_id_fkey REFERENCES companies (id) ON DELETE SET NULL
+    CONSTRAINT companies_parent_id_check CHECK (parent_id <> id);
+CREATE INDEX IF NOT EXISTS companies_parent_id_idx ON companies (parent_id) WHERE parent_id IS NOT NULL;
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP INDEX IF EXISTS companies_parent_id_idx;
+ALTER TABLE companies DROP COLUMN IF EXISTS parent_id;
+-- +migrate StatementEnd
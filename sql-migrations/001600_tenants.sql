@@ -0,0 +1,53 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- rows created before tenants belong to the default tenant
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+DROP INDEX IF EXISTS companies_code_country_key;
+CREATE UNIQUE INDEX companies_code_country_key ON companies (tenant_id, code, upper(country))
+    WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS companies_created_at_id_idx;
+CREATE INDEX IF NOT EXISTS companies_tenant_created_at_id_idx ON companies (tenant_id, created_at, id);
+DROP INDEX IF EXISTS companies_name_id_idx;
+CREATE INDEX IF NOT EXISTS companies_tenant_name_id_idx ON companies (tenant_id, name, id);
+
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (tenant_id, name);
+
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_pkey PRIMARY KEY (tenant_id, key);
+
+ALTER TABLE company_audit ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+ALTER TABLE company_outbox ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhook_subscriptions ADD COLUMN IF NOT EXISTS tenant_id varchar(64) NOT NULL DEFAULT 'default';
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DELETE FROM webhook_subscriptions WHERE tenant_id <> 'default';
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS tenant_id;
+DELETE FROM company_outbox WHERE tenant_id <> 'default';
+ALTER TABLE company_outbox DROP COLUMN IF EXISTS tenant_id;
+-- the history is append-only, records of all tenants are kept
+ALTER TABLE company_audit DROP COLUMN IF EXISTS tenant_id;
+
+DELETE FROM idempotency_keys WHERE tenant_id <> 'default';
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_pkey PRIMARY KEY (key);
+
+DELETE FROM tags WHERE tenant_id <> 'default';
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
+ALTER TABLE tags DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (name);
+
+DELETE FROM companies WHERE tenant_id <> 'default';
+DROP INDEX IF EXISTS companies_tenant_name_id_idx;
+CREATE INDEX IF NOT EXISTS companies_name_id_idx ON companies (name, id);
+DROP INDEX IF EXISTS companies_tenant_created_at_id_idx;
+CREATE INDEX IF NOT EXISTS companies_created_at_id_idx ON companies (created_at, id);
+DROP INDEX IF EXISTS companies_code_country_key;
+CREATE UNIQUE INDEX companies_code_country_key ON companies (code, upper(country)) WHERE deleted_at IS NULL;
+ALTER TABLE companies DROP COLUMN IF EXISTS tenant_id;
+-- +migrate StatementEnd
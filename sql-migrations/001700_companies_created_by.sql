@@ -0,0 +1,12 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- actors of the clients, empty for companies created before they were recorded
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS created_by varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS updated_by varchar(255) NOT NULL DEFAULT '';
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+ALTER TABLE companies DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE companies DROP COLUMN IF EXISTS created_by;
+-- +migrate StatementEnd
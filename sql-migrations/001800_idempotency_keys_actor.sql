@@ -0,0 +1,16 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- keys are unique per client, keys of the clients of one tenant do not collide
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS actor varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_pkey PRIMARY KEY (tenant_id, actor, key);
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+-- keys are short-lived, replaying them to another client is worse than losing them
+DELETE FROM idempotency_keys;
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS actor;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_pkey PRIMARY KEY (tenant_id, key);
+-- +migrate StatementEnd
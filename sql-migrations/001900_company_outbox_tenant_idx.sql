@@ -0,0 +1,10 @@
+-- +migrate Up
+-- +migrate StatementBegin
+-- events are read by tenant
+CREATE INDEX IF NOT EXISTS company_outbox_tenant_id_idx ON company_outbox (tenant_id, id);
+-- +migrate StatementEnd
+
+-- +migrate Down
+-- +migrate StatementBegin
+DROP INDEX IF EXISTS company_outbox_tenant_id_idx;
+-- +migrate StatementEnd